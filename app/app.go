@@ -2,42 +2,267 @@ package app
 
 import (
 	"fmt"
+	"log/slog"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/masamerc/sevp/pkg/sevp"
 )
 
 // App is the main application struct that holds the items to be displayed
 type App struct {
-	items    []string
-	teaItems []list.Item
-	target   string
+	items     []string
+	teaItems  []list.Item
+	target    string
+	apply     ApplyFunc
+	favorites Favorites
+	current   string
+	preview   PreviewFunc
+	styles    *StyleSet
+	dangerous func(item string) bool
+	danger    string
+	keys      KeyMap
+	unset     UnsetFunc
+	copyValue RenderFunc
+}
+
+// ApplyFunc applies the picked item, e.g. writes it to the state file, and returns a description of the result.
+type ApplyFunc func(item string) (string, error)
+
+// RenderFunc returns the value to write for the picked item.
+type RenderFunc func(item string) (string, error)
+
+// UnsetFunc removes the target variable, e.g. from the state file, and returns a description of the result.
+type UnsetFunc func() (string, error)
+
+// Favorites ranks the items by how they are used and pins them.
+type Favorites interface {
+	// Rank returns the items ranked by their usage, see sevp.Usage.Rank.
+	Rank(items []string) []sevp.RankedItem
+
+	// TogglePin pins the item, or unpins it if it is pinned, and reports whether it is pinned now.
+	TogglePin(item string) (bool, error)
+}
+
+// selectorFavorites are the favorites of a selector, persisted next to the state file of the root
+type selectorFavorites struct {
+	root     *sevp.Root
+	selector string
 }
 
-// NewApp initializes a new App instance with the provided items and target variable
-func NewApp(items []string, targetVar string) *App {
+// SelectorFavorites returns the favorites of the selector, persisted next to the state file of the root.
+func SelectorFavorites(root *sevp.Root, selector string) Favorites {
+	return selectorFavorites{root: root, selector: selector}
+}
+
+// Rank returns the items ranked by the usage of the selector.
+func (f selectorFavorites) Rank(items []string) []sevp.RankedItem {
+	usage, err := sevp.LoadUsage(f.root)
+	if err != nil {
+		slog.Warn("Failed to load usage, keeping the order of the items", "err", err)
+		usage = &sevp.Usage{}
+	}
+
+	return usage.Rank(f.selector, items)
+}
+
+// TogglePin pins or unpins the item of the selector.
+func (f selectorFavorites) TogglePin(item string) (bool, error) {
+	return sevp.TogglePin(f.root, f.selector, item)
+}
+
+// NewApp initializes a new App instance with the provided items, target shown in the title and apply function.
+func NewApp(items []string, target string, apply ApplyFunc) *App {
 	teaItems := make([]list.Item, len(items))
 	for i, itemString := range items {
-		teaItems[i] = Item(itemString)
+		teaItems[i] = Item{Value: itemString, Rank: sevp.RankOther}
 	}
 	return &App{
 		items:    items,
 		teaItems: teaItems,
-		target:   targetVar,
+		target:   target,
+		apply:    apply,
+		styles:   DefaultStyleSet(),
+		keys:     DefaultKeyMap(),
+	}
+}
+
+// WithStyles renders the app with the styles, e.g. of the theme of the config.
+func (a *App) WithStyles(styles *StyleSet) *App {
+	a.styles = styles
+	return a
+}
+
+// WithKeys handles the keys of the app with the key bindings, e.g. of the config.
+func (a *App) WithKeys(keys KeyMap) *App {
+	a.keys = keys
+	return a
+}
+
+// WithFavorites ranks the items of the app by their usage and allows pinning them.
+func (a *App) WithFavorites(favorites Favorites) *App {
+	a.favorites = favorites
+	a.teaItems = listItems(favorites.Rank(a.items))
+	return a
+}
+
+// WithCurrent marks the currently active item and starts the cursor on it.
+func (a *App) WithCurrent(item string) *App {
+	a.current = item
+	return a
+}
+
+// WithPreview allows showing the preview of the selected item in a pane next to the list.
+func (a *App) WithPreview(preview PreviewFunc) *App {
+	a.preview = preview
+	return a
+}
+
+// WithDanger marks dangerous items, shown in the colour and picked only after a confirmation.
+// An empty colour is the error colour of the styles.
+func (a *App) WithDanger(dangerous func(item string) bool, colour string) *App {
+	a.dangerous = dangerous
+	a.danger = colour
+	return a
+}
+
+// WithUnset allows unsetting the target variable instead of picking an item.
+func (a *App) WithUnset(unset UnsetFunc) *App {
+	a.unset = unset
+	return a
+}
+
+// WithCopy allows copying the value rendered from the selected item to the clipboard.
+func (a *App) WithCopy(render RenderFunc) *App {
+	a.copyValue = render
+	return a
+}
+
+// WriteValue returns an ApplyFunc writing the value rendered from the picked item of the selector to the target variable
+// in the state file of the root, describing the result with the styles.
+func WriteValue(root *sevp.Root, selector string, targetVar string, render RenderFunc, styles *StyleSet) ApplyFunc {
+	return func(item string) (string, error) {
+		renderStyles := styles.Rendering
+
+		value, err := render(item)
+		if err != nil {
+			return "", fmt.Errorf("rendering value: %w", err)
+		}
+
+		assignment := sevp.Assignment{Selector: selector, TargetVar: targetVar, Item: item, Value: value}
+		if err := sevp.WriteAssignments(root, sevp.SourcePicker, []sevp.Assignment{assignment}); err != nil {
+			return "", fmt.Errorf("writing to file: %w", err)
+		}
+
+		// the usage only ranks the items, so failing to record it does not fail the pick
+		if err := sevp.RecordUse(root, selector, item); err != nil {
+			slog.Warn("Failed to record usage", "selector", selector, "item", item, "err", err)
+		}
+
+		selected := renderStyles.SelectedResult.Render(item)
+		if value != item {
+			selected += " -> " + renderStyles.SelectedResult.Render(value)
+		}
+
+		return fmt.Sprintf("%s selected: %s", renderStyles.TargetType.Render(targetVar), selected), nil
+	}
+}
+
+// UnsetValue returns an UnsetFunc removing the target variable from the state file of the root,
+// describing the result with the styles.
+func UnsetValue(root *sevp.Root, targetVar string, styles *StyleSet) UnsetFunc {
+	return func() (string, error) {
+		if err := sevp.UnsetValuesFrom(root, sevp.SourcePicker, []string{targetVar}); err != nil {
+			return "", fmt.Errorf("writing to file: %w", err)
+		}
+
+		// shells that already evaluated the state file keep the variable
+		return fmt.Sprintf("%s unset, run 'unset %s' to remove it from the current shell", styles.Rendering.TargetType.Render(targetVar), targetVar), nil
 	}
 }
 
 // Run starts the Bubble Tea program with the items and target variable
 // set in the App struct
 func (a *App) Run() error {
-	listStyles := NewStyleSet().List
-	renderStyles := NewStyleSet().Rendering
+	_, err := tea.NewProgram(a.model()).Run()
+	return err
+}
+
+// model builds the model of the value list of the app
+func (a *App) model() Model {
+	renderStyles := a.styles.Rendering
 
-	l := list.New(a.teaItems, NewItemDelegate(), DefaultWidth, ListHeight)
+	delegate := NewItemDelegate(a.styles)
+	delegate.Current = a.current
+	delegate.Dangerous = a.dangerous
+	delegate.DangerStyle = a.styles.DangerStyle(a.danger)
 
 	// title setting
-	title := fmt.Sprintf("[%s]\n\ntype '/' to search", renderStyles.TargetType.Render(a.target))
+	title := fmt.Sprintf("[%s]", renderStyles.TargetType.Render(a.target))
+	if a.current != "" {
+		title += fmt.Sprintf(" current: %s", renderStyles.SelectedResult.Render(a.current))
+	}
+
+	l := newList(a.teaItems, delegate, title, a.styles, a.keys)
+
+	// start on the current item
+	for index, item := range a.teaItems {
+		if item.(Item).Value == a.current {
+			l.Select(index)
+			break
+		}
+	}
+
+	// pinning is only offered for ranked items, previewing for items with a preview,
+	// and unsetting and copying if the app supports them
+	shortKeys := []key.Binding{a.keys.Pick}
+	if a.favorites != nil {
+		shortKeys = append(shortKeys, a.keys.Pin)
+	}
+	if a.preview != nil {
+		shortKeys = append(shortKeys, a.keys.Preview)
+	}
+	fullKeys := shortKeys
+	if a.unset != nil {
+		fullKeys = append(fullKeys, a.keys.Unset)
+	}
+	if a.copyValue != nil {
+		fullKeys = append(fullKeys, a.keys.Copy)
+	}
+	l.AdditionalShortHelpKeys = func() []key.Binding { return shortKeys }
+	l.AdditionalFullHelpKeys = func() []key.Binding { return fullKeys }
+
+	m := NewModel(l, a.apply, a.styles).WithKeys(a.keys).WithFavorites(a.items, a.favorites)
+	if a.preview != nil {
+		m = m.WithPreview(a.preview)
+	}
+	if a.unset != nil {
+		m = m.WithUnset(a.unset)
+	}
+	if a.copyValue != nil {
+		m = m.WithCopy(a.copyValue)
+	}
+	if a.dangerous != nil {
+		m = m.WithDanger(a.dangerous, delegate.DangerStyle)
+	}
+	return m
+}
+
+// newList creates a filterable list of the items with the title, styled with the styles and handling the keys
+func newList(items []list.Item, delegate list.ItemDelegate, title string, styles *StyleSet, keys KeyMap) list.Model {
+	listStyles := styles.List
+
+	l := list.New(items, delegate, DefaultWidth, ListHeight)
+	l.KeyMap = keys.List
+
+	// title setting, kept on a single line since the list truncates the title as a whole,
+	// so only the hint is cut off on narrow terminals
 	l.Title = title
+	if keys.List.Filter.Enabled() {
+		l.Title += fmt.Sprintf("  type '%s' to search", keys.List.Filter.Help().Key)
+	}
 
 	// general settings
 	l.SetShowStatusBar(false)
@@ -52,8 +277,5 @@ func (a *App) Run() error {
 	l.Styles.FilterCursor = listStyles.Styles.FilterCursor
 	l.Styles.PaginationStyle = listStyles.Styles.PaginationStyle
 
-	m := NewModel(l, a.target)
-
-	_, err := tea.NewProgram(m).Run()
-	return err
+	return l
 }
@@ -0,0 +1,230 @@
+package app
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SelectorItem is a selector shown in the selector list of the Chooser
+type SelectorItem struct {
+	Name      string
+	TargetVar string
+	Current   string
+}
+
+// FilterValue returns the name of the selector
+func (s SelectorItem) FilterValue() string { return s.Name }
+
+// OpenFunc returns the App picking a value of the selector
+type OpenFunc func(selector string) (*App, error)
+
+// Chooser is a two-level TUI, choosing a selector first and then its value
+type Chooser struct {
+	selectors []SelectorItem
+	open      OpenFunc
+	styles    *StyleSet
+	keys      KeyMap
+}
+
+// NewChooser initializes a new Chooser with the selectors and the function opening the value list of a selector.
+func NewChooser(selectors []SelectorItem, open OpenFunc) *Chooser {
+	return &Chooser{selectors: selectors, open: open, styles: DefaultStyleSet(), keys: DefaultKeyMap()}
+}
+
+// WithStyles renders the selector list with the styles, e.g. of the theme of the config.
+func (c *Chooser) WithStyles(styles *StyleSet) *Chooser {
+	c.styles = styles
+	return c
+}
+
+// WithKeys handles the keys of the selector list with the key bindings, e.g. of the config.
+// The value lists opened from it use the key bindings of their App.
+func (c *Chooser) WithKeys(keys KeyMap) *Chooser {
+	c.keys = keys
+	return c
+}
+
+// Run starts the Bubble Tea program with the selector list
+func (c *Chooser) Run() error {
+	renderStyles := c.styles.Rendering
+
+	items := make([]list.Item, len(c.selectors))
+	for i, s := range c.selectors {
+		items[i] = s
+	}
+
+	l := newList(items, selectorDelegate{styles: c.styles}, fmt.Sprintf("[%s]", renderStyles.TargetType.Render("selectors")), c.styles, c.keys)
+	l.AdditionalShortHelpKeys = func() []key.Binding { return []key.Binding{c.keys.Pick} }
+
+	_, err := tea.NewProgram(chooserModel{list: l, open: c.open, styles: c.styles, keys: c.keys}).Run()
+	return err
+}
+
+// chooserModel controls the state of the Chooser, showing the value list of the opened selector if any
+type chooserModel struct {
+	list     list.Model
+	open     OpenFunc
+	picker   *Model
+	styles   *StyleSet
+	keys     KeyMap
+	width    int
+	height   int
+	quitting bool
+	err      error
+}
+
+// Init is the first function that will be called. It returns an optional initial command.
+func (m chooserModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update processes messages and updates the chooser state
+func (m chooserModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if size, ok := msg.(tea.WindowSizeMsg); ok {
+		m.width, m.height = size.Width, size.Height
+	}
+
+	if m.picker != nil {
+		// go back to the selector list, unless the value list is being filtered or a pick confirmed
+		if msg, ok := msg.(tea.KeyMsg); ok && key.Matches(msg, m.keys.Back) && !m.picker.list.SettingFilter() && m.picker.confirming == "" {
+			m.picker = nil
+			return m, nil
+		}
+
+		picker, cmd := m.picker.Update(msg)
+		p := picker.(Model)
+		m.picker = &p
+		return m, cmd
+	}
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		// the view starts with an empty line
+		m.list.SetSize(msg.Width, msg.Height-1)
+		return m, nil
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			// CTRL+C always quits the application
+			m.quitting = true
+			return m, tea.Quit
+		}
+
+		if m.list.SettingFilter() {
+			// while filtering, the keys are typed into the filter
+			break
+		}
+
+		switch {
+		case key.Matches(msg, m.keys.Pick):
+			if s, ok := m.list.SelectedItem().(SelectorItem); ok {
+				m.openPicker(s.Name)
+			}
+			return m, nil
+		case key.Matches(msg, m.keys.List.Quit):
+			m.quitting = true
+			return m, tea.Quit
+		}
+	}
+
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+// openPicker opens the value list of the selector, keeping the selector list open if that fails
+func (m *chooserModel) openPicker(selector string) {
+	a, err := m.open(selector)
+	if err != nil {
+		m.err = fmt.Errorf("selector %s: %w", selector, err)
+		return
+	}
+	m.err = nil
+
+	picker := a.model()
+	if m.width > 0 {
+		picker.resize(m.width, m.height)
+	}
+
+	// offer going back next to the other keys of the value list
+	picker.list.AdditionalShortHelpKeys = withBinding(m.keys.Back, picker.list.AdditionalShortHelpKeys)
+	picker.list.AdditionalFullHelpKeys = withBinding(m.keys.Back, picker.list.AdditionalFullHelpKeys)
+
+	m.picker = &picker
+}
+
+// withBinding returns the additional help keys of a list, prepended by the binding
+func withBinding(binding key.Binding, keys func() []key.Binding) func() []key.Binding {
+	return func() []key.Binding {
+		bindings := []key.Binding{binding}
+		if keys != nil {
+			bindings = append(bindings, keys()...)
+		}
+		return bindings
+	}
+}
+
+// View shows the value list of the opened selector, or the selector list
+func (m chooserModel) View() string {
+	renderStyles := m.styles.Rendering
+
+	if m.picker != nil {
+		return m.picker.View()
+	}
+
+	if m.quitting {
+		// we want to quit the application without making a selection
+		return renderStyles.QuitText.Render("Aborted.")
+	}
+
+	view := "\n" + m.list.View()
+	if m.err != nil {
+		view += "\n" + renderStyles.QuitText.Render("Error "+m.err.Error())
+	}
+	return view
+}
+
+// selectorDelegate renders the selectors of the Chooser with their current values
+type selectorDelegate struct {
+	styles *StyleSet
+}
+
+// Height defines the height of the selector in the list
+func (d selectorDelegate) Height() int { return 1 }
+
+// Spacing defines the spacing between selectors in the list
+func (d selectorDelegate) Spacing() int { return 0 }
+
+// Update is a no-op for the selector delegate
+func (d selectorDelegate) Update(msg tea.Msg, m *list.Model) tea.Cmd { return nil }
+
+// Render renders the selector, followed by its target variable and current value
+func (d selectorDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
+	renderStyles := d.styles.Rendering
+	s, ok := listItem.(SelectorItem)
+	if !ok {
+		return
+	}
+
+	fn := renderStyles.Item.Render
+	if index == m.Index() {
+		fn = func(strs ...string) string {
+			return renderStyles.SelectedItem.Render("> " + strs[0])
+		}
+	}
+
+	tag := ""
+	if s.TargetVar != "" {
+		current := s.Current
+		if current == "" {
+			current = "(unset)"
+		}
+		tag = renderStyles.Tag.Render(fmt.Sprintf(" %s = %s", s.TargetVar, current))
+	}
+
+	fmt.Fprint(w, fn(s.Name)+tag)
+}
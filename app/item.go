@@ -6,20 +6,48 @@ import (
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/masamerc/sevp/pkg/sevp"
 )
 
 // Item represents a single item in the list
-type Item string
+type Item struct {
+	Value string
+
+	// Rank is the section of the list the item is shown in
+	Rank sevp.Rank
+}
 
 // FilterValue returns the string representation of the item
-func (i Item) FilterValue() string { return string(i) }
+func (i Item) FilterValue() string { return i.Value }
+
+// listItems converts the ranked items into list items
+func listItems(ranked []sevp.RankedItem) []list.Item {
+	items := make([]list.Item, len(ranked))
+	for i, r := range ranked {
+		items[i] = Item{Value: r.Value, Rank: r.Rank}
+	}
+	return items
+}
 
 // ItemDelegate is a custom delegate for rendering items in the list
-type ItemDelegate struct{}
+type ItemDelegate struct {
+	// Current is the currently active item, marked with a badge
+	Current string
+
+	// Dangerous reports whether an item is dangerous, rendering it with the DangerStyle
+	Dangerous func(item string) bool
+
+	// DangerStyle is the style of dangerous items
+	DangerStyle lipgloss.Style
 
-// NewItemDelegate creates a new empty instance of ItemDelegate
-func NewItemDelegate() ItemDelegate {
-	return ItemDelegate{}
+	styles *StyleSet
+}
+
+// NewItemDelegate creates a new instance of ItemDelegate rendering items with the styles
+func NewItemDelegate(styles *StyleSet) ItemDelegate {
+	return ItemDelegate{styles: styles}
 }
 
 // Height defines the height of the item in the list
@@ -34,24 +62,46 @@ func (d ItemDelegate) Update(msg tea.Msg, m *list.Model) tea.Cmd { return nil }
 // Render is the main rendering function for the item delegate.
 // It determines how the item should be displayed based on its index.
 func (d ItemDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
-	renderStyles := NewStyleSet().Rendering
+	renderStyles := d.styles.Rendering
 	i, ok := listItem.(Item)
 	if !ok {
 		return
 	}
 
-	str := string(i)
+	str := i.Value
+
+	itemStyle, selectedStyle := renderStyles.Item, renderStyles.SelectedItem
+	dangerous := d.Dangerous != nil && d.Dangerous(i.Value)
+	if dangerous {
+		itemStyle = itemStyle.Inherit(d.DangerStyle)
+		selectedStyle = selectedStyle.Foreground(d.DangerStyle.GetForeground())
+	}
 
 	// default render function / style for each item
-	fn := renderStyles.Item.Render
+	fn := itemStyle.Render
 
 	// if the item is selected, apply a different style and format
 	if index == m.Index() {
 		fn = func(strs ...string) string {
-			return renderStyles.SelectedItem.Render("> " + strs[0])
+			return selectedStyle.Render("> " + strs[0])
 		}
 	}
 
+	// tag the dangerous, current, pinned and recently used items
+	tag := ""
+	if dangerous {
+		tag += d.DangerStyle.Render(" ⚠")
+	}
+	if d.Current != "" && i.Value == d.Current {
+		tag += renderStyles.Current.Render(" ● current")
+	}
+	switch i.Rank {
+	case sevp.RankPinned:
+		tag += renderStyles.Tag.Render(" (pinned)")
+	case sevp.RankRecent:
+		tag += renderStyles.Tag.Render(" (recent)")
+	}
+
 	// render the item with the selected style
-	fmt.Fprint(w, fn(str))
+	fmt.Fprint(w, fn(str)+tag)
 }
@@ -0,0 +1,141 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+
+	"github.com/masamerc/sevp/pkg/sevp"
+)
+
+// KeyMap holds the key bindings of the TUI
+type KeyMap struct {
+	// List are the bindings of the list, e.g. moving the cursor, searching and quitting
+	List list.KeyMap
+
+	// Pick picks the selected item
+	Pick key.Binding
+
+	// Back returns from the value list to the selector list
+	Back key.Binding
+
+	// Pin pins and unpins the selected item
+	Pin key.Binding
+
+	// Preview shows and hides the preview pane
+	Preview key.Binding
+
+	// Unset removes the target variable of the selector
+	Unset key.Binding
+
+	// Copy copies the value of the selected item to the clipboard
+	Copy key.Binding
+
+	// Confirm confirms picking a dangerous item, any other key cancels
+	Confirm key.Binding
+}
+
+// keyPresets builds the key bindings of the built-in presets, see sevp.KeyPresets
+var keyPresets = map[string]func() KeyMap{
+	sevp.KeysDefault: defaultKeys,
+	sevp.KeysVim:     vimKeys,
+	sevp.KeysEmacs:   emacsKeys,
+}
+
+// defaultKeys are the bindings of the list, extended by the actions of sevp
+func defaultKeys() KeyMap {
+	return KeyMap{
+		List:    list.DefaultKeyMap(),
+		Pick:    key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "pick")),
+		Back:    key.NewBinding(key.WithKeys("esc", "backspace"), key.WithHelp("esc", "back")),
+		Pin:     key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "pin/unpin")),
+		Preview: key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "preview")),
+		Unset:   key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "unset")),
+		Copy:    key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "copy")),
+		Confirm: key.NewBinding(key.WithKeys("y", "Y"), key.WithHelp("y", "confirm")),
+	}
+}
+
+// vimKeys move with hjkl, page with ctrl+u and ctrl+d, mark pins with m and yank values with y
+func vimKeys() KeyMap {
+	keys := defaultKeys()
+	rebind(&keys.List.PrevPage, []string{"ctrl+u", "ctrl+b", "pgup"})
+	rebind(&keys.List.NextPage, []string{"ctrl+d", "ctrl+f", "pgdown"})
+	rebind(&keys.Pick, []string{"enter", "l"})
+	rebind(&keys.Back, []string{"esc", "backspace", "h"})
+	rebind(&keys.Pin, []string{"m"})
+	rebind(&keys.Copy, []string{"y"})
+	return keys
+}
+
+// emacsKeys move with ctrl+p and ctrl+n, page with ctrl+v and alt+v, search with ctrl+s and quit with ctrl+g
+func emacsKeys() KeyMap {
+	keys := defaultKeys()
+	rebind(&keys.List.CursorUp, []string{"ctrl+p", "up"})
+	rebind(&keys.List.CursorDown, []string{"ctrl+n", "down"})
+	rebind(&keys.List.PrevPage, []string{"alt+v", "pgup"})
+	rebind(&keys.List.NextPage, []string{"ctrl+v", "pgdown"})
+	rebind(&keys.List.GoToStart, []string{"alt+<", "home"})
+	rebind(&keys.List.GoToEnd, []string{"alt+>", "end"})
+	rebind(&keys.List.Filter, []string{"ctrl+s", "/"})
+	rebind(&keys.List.CancelWhileFiltering, []string{"ctrl+g", "esc"})
+	rebind(&keys.List.Quit, []string{"ctrl+g", "q", "esc"})
+	rebind(&keys.Back, []string{"ctrl+b", "esc", "backspace"})
+	rebind(&keys.Pin, []string{"alt+p"})
+	rebind(&keys.Unset, []string{"ctrl+k"})
+	rebind(&keys.Copy, []string{"alt+w"})
+	return keys
+}
+
+// NewKeyMap returns the key bindings of the keys: the bindings of its preset, replaced by the bindings it sets.
+//
+// ctrl+c always quits, whatever the bindings are.
+func NewKeyMap(keys sevp.Keys) KeyMap {
+	preset, ok := keyPresets[keys.Preset]
+	if !ok {
+		preset = keyPresets[sevp.KeysDefault]
+	}
+	km := preset()
+
+	rebind(&km.Pick, keys.Pick)
+	rebind(&km.List.Quit, keys.Quit)
+	rebind(&km.Back, keys.Back)
+	rebind(&km.List.CursorUp, keys.Up)
+	rebind(&km.List.CursorDown, keys.Down)
+	rebind(&km.List.PrevPage, keys.PrevPage)
+	rebind(&km.List.NextPage, keys.NextPage)
+	rebind(&km.List.GoToStart, keys.First)
+	rebind(&km.List.GoToEnd, keys.Last)
+	rebind(&km.List.Filter, keys.Search)
+	rebind(&km.List.ShowFullHelp, keys.Help)
+	rebind(&km.List.CloseFullHelp, keys.Help)
+	rebind(&km.Pin, keys.Pin)
+	rebind(&km.Preview, keys.Preview)
+	rebind(&km.Unset, keys.Unset)
+	rebind(&km.Copy, keys.Copy)
+	rebind(&km.Confirm, keys.Confirm)
+
+	return km
+}
+
+// DefaultKeyMap returns the key bindings of the default preset
+func DefaultKeyMap() KeyMap {
+	return NewKeyMap(sevp.Keys{})
+}
+
+// rebind replaces the keys of the binding, keeping its help text. Nil keys keep the binding, no keys disable it.
+func rebind(binding *key.Binding, keys []string) {
+	if keys == nil {
+		return
+	}
+
+	if len(keys) == 0 {
+		binding.SetEnabled(false)
+		return
+	}
+
+	binding.SetKeys(keys...)
+	binding.SetHelp(strings.Join(keys, "/"), binding.Help().Desc)
+	binding.SetEnabled(true)
+}
@@ -3,23 +3,83 @@ package app
 import (
 	"fmt"
 
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
-
-	"github.com/masamerc/sevp/internal"
+	"github.com/charmbracelet/lipgloss"
 )
 
 // Model controls the state of the TUI application
 type Model struct {
-	list     list.Model
-	choice   string
-	quitting bool
-	target   string
+	list      list.Model
+	choice    string
+	quitting  bool
+	apply     ApplyFunc
+	result    string
+	err       error
+	items     []string
+	favorites Favorites
+	styles    *StyleSet
+	keys      KeyMap
+
+	unset     UnsetFunc
+	unsetDone bool
+	copyValue RenderFunc
+
+	preview     PreviewFunc
+	previews    map[string]previewResult
+	showPreview bool
+	width       int
+	height      int
+
+	dangerous   func(item string) bool
+	dangerStyle lipgloss.Style
+	confirming  string
+}
+
+// NewModel creates a new instance of the Model with the provided list, apply function and styles
+func NewModel(l list.Model, apply ApplyFunc, styles *StyleSet) Model {
+	return Model{list: l, apply: apply, styles: styles, keys: DefaultKeyMap()}
+}
+
+// WithKeys handles the keys with the key bindings, e.g. of the config
+func (m Model) WithKeys(keys KeyMap) Model {
+	m.keys = keys
+	return m
+}
+
+// WithUnset allows unsetting the target variable instead of picking an item
+func (m Model) WithUnset(unset UnsetFunc) Model {
+	m.unset = unset
+	return m
+}
+
+// WithCopy allows copying the value rendered from the selected item to the clipboard
+func (m Model) WithCopy(render RenderFunc) Model {
+	m.copyValue = render
+	return m
+}
+
+// WithFavorites allows pinning the items, which are re-ranked by the favorites after every change
+func (m Model) WithFavorites(items []string, favorites Favorites) Model {
+	m.items = items
+	m.favorites = favorites
+	return m
 }
 
-// NewModel creates a new instance of the Model with the provided list and target variable
-func NewModel(l list.Model, target string) Model {
-	return Model{list: l, target: target}
+// WithPreview allows showing the preview of the selected item next to the list
+func (m Model) WithPreview(preview PreviewFunc) Model {
+	m.preview = preview
+	m.previews = make(map[string]previewResult)
+	return m
+}
+
+// WithDanger asks for a confirmation before applying dangerous items
+func (m Model) WithDanger(dangerous func(item string) bool, style lipgloss.Style) Model {
+	m.dangerous = dangerous
+	m.dangerStyle = style
+	return m
 }
 
 // Init is a no-op for the model
@@ -31,30 +91,55 @@ func (m Model) Init() tea.Cmd {
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
-		m.list.SetWidth(msg.Width)
+		m.resize(msg.Width, msg.Height)
 		return m, nil
 
 	case tea.KeyMsg:
-		switch key := msg.String(); key {
-		case "ctrl+c":
+		if m.confirming != "" {
+			return m.confirm(msg)
+		}
+
+		if msg.String() == "ctrl+c" {
 			// CTRL+C always quits the application
 			m.quitting = true
 			return m, tea.Quit
-		case "enter":
-			if m.list.FilterState() != list.Filtering {
-				// If not in filtering mode, and users presses enter, we want to select the item
-				i, ok := m.list.SelectedItem().(Item)
-				if ok {
-					m.choice = string(i)
-				}
-				return m, tea.Quit
+		}
+
+		if m.list.SettingFilter() {
+			// while filtering, the keys are typed into the filter
+			break
+		}
+
+		switch {
+		case key.Matches(msg, m.keys.Pick):
+			i, ok := m.list.SelectedItem().(Item)
+			if ok && m.dangerous != nil && m.dangerous(i.Value) {
+				// dangerous items are only applied once they are confirmed
+				m.confirming = i.Value
+				m.resize(m.width, m.height)
+				return m, nil
 			}
-		default:
-			// If not in filtering mode, and users presses 'q' or 'esc', we want to quit
-			if !m.list.SettingFilter() && (key == "q" || key == "esc") {
-				m.quitting = true
-				return m, tea.Quit
+			if ok {
+				// apply the selected item once, before quitting the application
+				m.choice = i.Value
+				m.result, m.err = m.apply(m.choice)
 			}
+			return m, tea.Quit
+		case m.favorites != nil && key.Matches(msg, m.keys.Pin):
+			return m, m.togglePin()
+		case m.preview != nil && key.Matches(msg, m.keys.Preview):
+			m.showPreview = !m.showPreview
+			m.resize(m.width, m.height)
+			return m, nil
+		case m.unset != nil && key.Matches(msg, m.keys.Unset):
+			m.result, m.err = m.unset()
+			m.unsetDone = true
+			return m, tea.Quit
+		case m.copyValue != nil && key.Matches(msg, m.keys.Copy):
+			return m, m.copySelected()
+		case key.Matches(msg, m.keys.List.Quit):
+			m.quitting = true
+			return m, tea.Quit
 		}
 	}
 
@@ -63,25 +148,126 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// confirm applies the dangerous item being confirmed if a confirm key is pressed, and cancels the confirmation otherwise
+func (m Model) confirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case msg.String() == "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+	case key.Matches(msg, m.keys.Confirm):
+		m.choice = m.confirming
+		m.result, m.err = m.apply(m.choice)
+		return m, tea.Quit
+	}
+
+	m.confirming = ""
+	m.resize(m.width, m.height)
+	return m, nil
+}
+
+// resize fits the list and the preview pane, if shown, into the window size
+func (m *Model) resize(width int, height int) {
+	m.width, m.height = width, height
+
+	listWidth := width
+	if m.showPreview {
+		listWidth = width / 2
+	}
+	if listWidth <= 0 {
+		listWidth = DefaultWidth
+	}
+
+	// the view starts with an empty line, and ends with the confirmation prompt while confirming
+	listHeight := height - 1
+	if height <= 0 {
+		listHeight = ListHeight
+	}
+	if m.confirming != "" {
+		listHeight--
+	}
+
+	m.list.SetSize(listWidth, listHeight)
+}
+
+// previewView renders the preview pane of the selected item, previewing each item once
+func (m Model) previewView() string {
+	i, ok := m.list.SelectedItem().(Item)
+	if !ok {
+		return ""
+	}
+
+	result, ok := m.previews[i.Value]
+	if !ok {
+		result.preview, result.err = m.preview(i.Value)
+		m.previews[i.Value] = result
+	}
+
+	width := m.width - m.list.Width()
+	if width <= 0 {
+		width = DefaultWidth
+	}
+
+	// leave room for the border and padding of the pane
+	return renderPreview(m.styles, i.Value, result, width-4)
+}
+
+// togglePin pins or unpins the selected item and re-ranks the items, keeping the item selected
+func (m *Model) togglePin() tea.Cmd {
+	i, ok := m.list.SelectedItem().(Item)
+	if !ok {
+		return nil
+	}
+
+	if _, err := m.favorites.TogglePin(i.Value); err != nil {
+		return m.list.NewStatusMessage(m.styles.Rendering.Error.Render("Error " + err.Error()))
+	}
+
+	ranked := m.favorites.Rank(m.items)
+	cmd := m.list.SetItems(listItems(ranked))
+
+	if m.list.FilterState() == list.Unfiltered {
+		for index, r := range ranked {
+			if r.Value == i.Value {
+				m.list.Select(index)
+				break
+			}
+		}
+	}
+
+	return cmd
+}
+
+// copySelected copies the value rendered from the selected item to the clipboard, reporting the result next to the title
+func (m *Model) copySelected() tea.Cmd {
+	renderStyles := m.styles.Rendering
+
+	i, ok := m.list.SelectedItem().(Item)
+	if !ok {
+		return nil
+	}
+
+	value, err := m.copyValue(i.Value)
+	if err == nil {
+		err = clipboard.WriteAll(value)
+	}
+	if err != nil {
+		return m.list.NewStatusMessage(renderStyles.Error.Render("Error " + err.Error()))
+	}
+
+	return m.list.NewStatusMessage(renderStyles.Tag.Render("copied " + value))
+}
+
 // View defines what the model should display and
 // how to terminate the application based on user actions.
 func (m Model) View() string {
-	renderStyles := NewStyleSet().Rendering
-
-	if m.choice != "" {
-		// if users made a selection, we want to write the selected item to the target file
-		// and quit the application
-		err := internal.WriteToFile(m.choice, m.target)
-		if err != nil {
-			return renderStyles.QuitText.Render("Error writing to file: " + err.Error())
+	renderStyles := m.styles.Rendering
+
+	if m.choice != "" || m.unsetDone {
+		// if users made a selection or unset the variable, show the result
+		if m.err != nil {
+			return renderStyles.QuitText.Render("Error " + m.err.Error())
 		}
-		return renderStyles.PlainText.Render(
-			fmt.Sprintf(
-				"%s selected: %s",
-				renderStyles.TargetType.Render(m.target),
-				renderStyles.SelectedResult.Render(m.choice),
-			),
-		)
+		return renderStyles.PlainText.Render(m.result)
 	}
 
 	if m.quitting {
@@ -89,6 +275,16 @@ func (m Model) View() string {
 		return renderStyles.QuitText.Render("Aborted.")
 	}
 
-	// no selection made, and not quitting -> show the list
-	return "\n" + m.list.View()
+	// no selection made, and not quitting -> show the list, and the preview pane if shown
+	view := m.list.View()
+	if m.showPreview {
+		view = lipgloss.JoinHorizontal(lipgloss.Top, view, m.previewView())
+	}
+
+	if m.confirming != "" {
+		prompt := fmt.Sprintf("⚠ %s is marked as dangerous. Press %s to pick it, any other key to cancel.", m.confirming, m.keys.Confirm.Help().Key)
+		view += "\n" + m.dangerStyle.PaddingLeft(4).Render(prompt)
+	}
+
+	return "\n" + view
 }
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Preview describes what picking an item does, shown in the preview pane
+type Preview struct {
+	// Metadata describes the item, e.g. the region of an AWS profile
+	Metadata map[string]string
+
+	// Changes are the variables picking the item writes
+	Changes []Change
+}
+
+// Change is a variable written by picking an item
+type Change struct {
+	Var string
+
+	// Old is the current value of the variable, or nil if it is not set
+	Old *string
+
+	New string
+}
+
+// PreviewFunc returns the preview of the item
+type PreviewFunc func(item string) (Preview, error)
+
+// previewResult is a cached preview of an item
+type previewResult struct {
+	preview Preview
+	err     error
+}
+
+// renderPreview renders the preview pane of the item with the given width and styles
+func renderPreview(styles *StyleSet, item string, result previewResult, width int) string {
+	renderStyles := styles.Rendering
+
+	var b strings.Builder
+	b.WriteString(renderStyles.TargetType.Render(item) + "\n")
+
+	if result.err != nil {
+		b.WriteString("\n" + renderStyles.Error.Render("Error "+result.err.Error()))
+		return renderStyles.Preview.Width(width).Render(b.String())
+	}
+
+	for _, change := range result.preview.Changes {
+		b.WriteString("\n" + change.Var + "\n")
+
+		switch {
+		case change.Old == nil:
+			b.WriteString(renderStyles.Tag.Render("  (unset)") + " -> " + renderStyles.SelectedResult.Render(change.New) + "\n")
+		case *change.Old == change.New:
+			b.WriteString("  " + change.New + renderStyles.Tag.Render(" (unchanged)") + "\n")
+		default:
+			b.WriteString("  " + *change.Old + " -> " + renderStyles.SelectedResult.Render(change.New) + "\n")
+		}
+	}
+
+	if len(result.preview.Metadata) > 0 {
+		keys := make([]string, 0, len(result.preview.Metadata))
+		for key := range result.preview.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		b.WriteString("\n")
+		for _, key := range keys {
+			b.WriteString(fmt.Sprintf("%s %s\n", renderStyles.Tag.Render(key+":"), result.preview.Metadata[key]))
+		}
+	}
+
+	return renderStyles.Preview.Width(width).Render(strings.TrimRight(b.String(), "\n"))
+}
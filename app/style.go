@@ -1,19 +1,95 @@
 package app
 
 import (
+	"os"
+
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/lipgloss"
+
+	"github.com/masamerc/sevp/pkg/sevp"
 )
 
 const (
 	HexWhite        = "#FFFFFF"
 	HexBrightPurple = "#B198E5"
 	HexBrightGreen  = "#3CCE92"
+	HexBrightRed    = "#F25D94"
 
 	DefaultWidth = 30
 	ListHeight   = 15
 )
 
+// Palette holds the colours of a theme
+type Palette struct {
+	Accent    lipgloss.TerminalColor
+	Highlight lipgloss.TerminalColor
+	Error     lipgloss.TerminalColor
+	Text      lipgloss.TerminalColor
+}
+
+// palettes holds the colours of the built-in themes, see sevp.ThemePresets
+var palettes = map[string]Palette{
+	sevp.ThemeDark: {
+		Accent:    lipgloss.Color(HexBrightPurple),
+		Highlight: lipgloss.Color(HexBrightGreen),
+		Error:     lipgloss.Color(HexBrightRed),
+		Text:      lipgloss.Color(HexWhite),
+	},
+	sevp.ThemeLight: {
+		Accent:    lipgloss.Color("#6A4FB3"),
+		Highlight: lipgloss.Color("#1B8A5A"),
+		Error:     lipgloss.Color("#C4215F"),
+		Text:      lipgloss.Color("#1A1A1A"),
+	},
+	sevp.ThemeHighContrast: {
+		Accent:    lipgloss.Color("#00FFFF"),
+		Highlight: lipgloss.Color("#FFFF00"),
+		Error:     lipgloss.Color("#FF0000"),
+		Text:      lipgloss.Color("#FFFFFF"),
+	},
+}
+
+// ThemePalette returns the colours of the theme: the colours of its preset, overridden by the colours it sets.
+//
+// No colours are used at all if NO_COLOR is set, see https://no-color.org.
+func ThemePalette(theme sevp.Theme) Palette {
+	if noColor() {
+		return Palette{Accent: lipgloss.NoColor{}, Highlight: lipgloss.NoColor{}, Error: lipgloss.NoColor{}, Text: lipgloss.NoColor{}}
+	}
+
+	palette, ok := palettes[theme.Preset]
+	if !ok {
+		palette = palettes[sevp.ThemeDark]
+	}
+
+	override := func(colour *lipgloss.TerminalColor, value string) {
+		if value != "" {
+			*colour = lipgloss.Color(value)
+		}
+	}
+	override(&palette.Accent, theme.Accent)
+	override(&palette.Highlight, theme.Highlight)
+	override(&palette.Error, theme.Error)
+	override(&palette.Text, theme.Text)
+
+	return palette
+}
+
+// DangerStyle returns the style of dangerous items in the colour, or in the error colour of the styles if it is empty.
+func (s *StyleSet) DangerStyle(colour string) lipgloss.Style {
+	style := s.Rendering.Error
+	if colour != "" && !noColor() {
+		style = style.Foreground(lipgloss.Color(colour))
+	}
+
+	return style
+}
+
+// noColor reports whether colours are disabled, see https://no-color.org.
+func noColor() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
 // RenderingStyles holds the styles for rendering different components
 type RenderingStyles struct {
 	Item           lipgloss.Style
@@ -22,6 +98,10 @@ type RenderingStyles struct {
 	QuitText       lipgloss.Style
 	PlainText      lipgloss.Style
 	TargetType     lipgloss.Style
+	Tag            lipgloss.Style
+	Current        lipgloss.Style
+	Preview        lipgloss.Style
+	Error          lipgloss.Style
 }
 
 // ListStyles holds the styles for the list (ItemDelegade) component
@@ -35,25 +115,34 @@ type StyleSet struct {
 	List      ListStyles
 }
 
-// NewStyleSet creates a new StyleSet with default styles
-func NewStyleSet() *StyleSet {
+// NewStyleSet creates a new StyleSet with the colours of the palette
+func NewStyleSet(palette Palette) *StyleSet {
 	return &StyleSet{
 		Rendering: RenderingStyles{
 			Item:           lipgloss.NewStyle().PaddingLeft(4),
-			SelectedItem:   lipgloss.NewStyle().PaddingLeft(2).Foreground(lipgloss.Color(HexBrightGreen)).Bold(true),
-			SelectedResult: lipgloss.NewStyle().Foreground(lipgloss.Color(HexBrightGreen)).Bold(true),
+			SelectedItem:   lipgloss.NewStyle().PaddingLeft(2).Foreground(palette.Highlight).Bold(true),
+			SelectedResult: lipgloss.NewStyle().Foreground(palette.Highlight).Bold(true),
 			QuitText:       lipgloss.NewStyle().Margin(1, 0, 1, 4),
 			PlainText:      lipgloss.NewStyle().Margin(1, 0, 1, 4),
-			TargetType:     lipgloss.NewStyle().Foreground(lipgloss.Color(HexBrightPurple)).Bold(true),
+			TargetType:     lipgloss.NewStyle().Foreground(palette.Accent).Bold(true),
+			Tag:            lipgloss.NewStyle().Faint(true),
+			Current:        lipgloss.NewStyle().Foreground(palette.Highlight),
+			Preview:        lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(palette.Accent).Padding(0, 1),
+			Error:          lipgloss.NewStyle().Foreground(palette.Error).Bold(true),
 		},
 		List: ListStyles{
 			Styles: list.Styles{
 				Title:           lipgloss.NewStyle().MarginLeft(2).Bold(true),
 				PaginationStyle: lipgloss.NewStyle().PaddingLeft(4),
-				HelpStyle:       lipgloss.NewStyle().PaddingLeft(4).PaddingBottom(1).Foreground(lipgloss.Color(HexBrightPurple)),
-				FilterPrompt:    lipgloss.NewStyle().MarginLeft(2).Foreground(lipgloss.Color(HexWhite)).Bold(true),
-				FilterCursor:    lipgloss.NewStyle().Foreground(lipgloss.Color(HexWhite)),
+				HelpStyle:       lipgloss.NewStyle().PaddingLeft(4).PaddingBottom(1).Foreground(palette.Accent),
+				FilterPrompt:    lipgloss.NewStyle().MarginLeft(2).Foreground(palette.Text).Bold(true),
+				FilterCursor:    lipgloss.NewStyle().Foreground(palette.Text),
 			},
 		},
 	}
 }
+
+// DefaultStyleSet creates a new StyleSet with the colours of the default theme
+func DefaultStyleSet() *StyleSet {
+	return NewStyleSet(ThemePalette(sevp.Theme{}))
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/masamerc/sevp/pkg/sevp"
+)
+
+func init() {
+	rootCmd.AddCommand(allowCmd)
+	rootCmd.AddCommand(denyCmd)
+}
+
+// allowCmd allows the directory values of the project configs of the working directory.
+var allowCmd = &cobra.Command{
+	Use:   "allow",
+	Short: "Allow the directory values of the project configs of the working directory",
+	Long: `Allow the directory values of the project configs of the working directory, i.e. the .sevp.toml and .sevp-values
+files from the working directory up to the root of the repository.
+
+Directory values are only applied by the shell hook once they are allowed. Any change to the files blocks them again,
+so review the files before allowing them.`,
+	Args:              cobra.NoArgs,
+	PersistentPreRunE: initRoot,
+	RunE:              runAllow,
+}
+
+// denyCmd blocks the directory values of the project configs of the working directory.
+var denyCmd = &cobra.Command{
+	Use:               "deny",
+	Short:             "Block the directory values of the project configs of the working directory",
+	Args:              cobra.NoArgs,
+	PersistentPreRunE: initRoot,
+	RunE:              runDeny,
+}
+
+// runAllow executes the allow command, allowing the project files of the working directory.
+func runAllow(cmd *cobra.Command, args []string) error {
+	return updateProjectFiles(cmd, sevp.AllowProjectFiles, "allowed")
+}
+
+// runDeny executes the deny command, blocking the project files of the working directory.
+func runDeny(cmd *cobra.Command, args []string) error {
+	return updateProjectFiles(cmd, sevp.DenyProjectFiles, "blocked")
+}
+
+// updateProjectFiles updates the allowance of the project files of the working directory, printing them with the verb.
+func updateProjectFiles(cmd *cobra.Command, update func(*sevp.Root, []string) error, verb string) error {
+	cmd.SilenceUsage = true
+	root := rootFrom(cmd)
+
+	paths, err := sevp.FindProjectConfigs(root, root.Workdir)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errors.New("no project configs found in the working directory or above")
+	}
+
+	if err := update(root, paths); err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", verb, path)
+	}
+	return nil
+}
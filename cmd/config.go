@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/masamerc/sevp/pkg/sevp"
+)
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configSourcesCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configSchemaCmd)
+}
+
+// configCmd groups the commands that inspect and manage the configuration.
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and manage the configuration",
+}
+
+// configSourcesCmd shows which config files each selector came from.
+var configSourcesCmd = &cobra.Command{
+	Use:   "sources",
+	Short: "Show which config files each selector came from",
+	Args:  cobra.NoArgs,
+	Run:   runConfigSources,
+}
+
+// configValidateCmd checks the configuration for problems.
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file...]",
+	Short: "Check the configuration for problems",
+	Long: `Check the configuration for problems and report each of them with its file and line.
+
+Without arguments, the layered configuration is validated. With arguments, only the given files are.
+Exits with a non-zero status if any problems are found.`,
+	// the given files are validated on their own, the layered config is only loaded without arguments
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) > 0 {
+			return initRoot(cmd, args)
+		}
+		return loadConfig(cmd, args)
+	},
+	RunE: runConfigValidate,
+}
+
+// runConfigValidate executes the config validate command, printing every problem found.
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	var diagnostics []sevp.Diagnostic
+
+	if len(args) == 0 {
+		diagnostics = configFrom(cmd).Validate()
+	}
+
+	for _, file := range args {
+		cfg, err := sevp.LoadConfig(rootFrom(cmd), file)
+		if err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		diagnostics = append(diagnostics, cfg.Validate()...)
+	}
+
+	// Styling
+	styles := stylesFrom(cmd).Rendering
+	errorStyle := styles.Error
+	highlightStyle := styles.SelectedResult
+
+	for _, diagnostic := range diagnostics {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", errorStyle.Render("error:"), diagnostic)
+	}
+
+	if len(diagnostics) > 0 {
+		cmd.SilenceUsage = true
+		return problemsFound(len(diagnostics))
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), highlightStyle.Render("config is valid"))
+	return nil
+}
+
+// configSchemaCmd prints the JSON schema of the config format.
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON schema of the config format",
+	Long: `Print the JSON schema of the config format.
+
+Editors with TOML schema support, e.g. Taplo or Even Better TOML, can use it to complete and validate config files.`,
+	Args: cobra.NoArgs,
+	// the schema doesn't depend on any config
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+	RunE: func(cmd *cobra.Command, args []string) error {
+		schema, err := sevp.JSONSchema()
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(cmd.OutOrStdout(), string(schema))
+		return nil
+	},
+}
+
+// problemsFound returns the error for the number of problems found in the config.
+func problemsFound(n int) error {
+	if n == 1 {
+		return errors.New("1 problem found")
+	}
+
+	return fmt.Errorf("%d problems found", n)
+}
+
+// runConfigSources executes the config sources command, printing the config files of every selector.
+func runConfigSources(cmd *cobra.Command, args []string) {
+	cfg := configFrom(cmd)
+
+	// Styling
+	styles := stylesFrom(cmd).Rendering
+	accentStyle := styles.TargetType
+	highlightStyle := styles.SelectedResult
+
+	fmt.Fprintf(cmd.OutOrStdout(), "\nconfig files (lowest to highest precedence):\n")
+	for _, file := range cfg.Files {
+		fmt.Fprintf(cmd.OutOrStdout(), "  - %s\n", formatConfigFile(file))
+	}
+
+	if cfg.Default != "" {
+		fmt.Fprintf(cmd.OutOrStdout(), "\ndefault selector:\n  %s from %s\n",
+			highlightStyle.Render(cfg.Default), formatConfigFile(cfg.DefaultSource()))
+	}
+
+	names := cfg.Names()
+
+	// Calculate max width for padding
+	maxWidth := 0
+	for _, name := range names {
+		if len(name) > maxWidth {
+			maxWidth = len(name)
+		}
+	}
+	maxWidth += 2
+
+	fmt.Fprintf(cmd.OutOrStdout(), "\nselectors:\n")
+
+	for _, name := range names {
+		sources := make([]string, 0, len(cfg.Sources(name)))
+		for _, file := range cfg.Sources(name) {
+			sources = append(sources, formatConfigFile(file))
+		}
+
+		line := fmt.Sprintf("  %s %s", accentStyle.Render(fmt.Sprintf("%-*s", maxWidth, name)), strings.Join(sources, " -> "))
+		if locked := cfg.LockedKeys(name); len(locked) > 0 {
+			line += fmt.Sprintf(" (locked: %s)", strings.Join(locked, ", "))
+		}
+
+		fmt.Fprintln(cmd.OutOrStdout(), line)
+	}
+}
+
+// formatConfigFile formats a config file with its layer for display.
+func formatConfigFile(file sevp.ConfigFile) string {
+	if file.Path == "" {
+		return "<unknown>"
+	}
+
+	return file.String()
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/masamerc/sevp/pkg/sevp"
+)
+
+func init() {
+	configCmd.AddCommand(configConvertCmd)
+
+	configConvertCmd.Flags().String("to", "", "the format to convert to: toml, yaml or json (default by the extension of --output)")
+	configConvertCmd.Flags().StringP("output", "o", "", "write the converted config to this file instead of stdout")
+}
+
+// configConvertCmd converts a config file into another format.
+var configConvertCmd = &cobra.Command{
+	Use:   "convert [file]",
+	Short: "Convert a config file to TOML, YAML or JSON",
+	Long: `Convert a config file, the user config file by default, to TOML, YAML or JSON.
+
+The format of the file is detected by its extension. Comments are not carried over.`,
+	Example: `  sevp config convert --to yaml
+  sevp config convert ~/.config/sevp.toml -o ~/.config/sevp.json`,
+	Args:              cobra.MaximumNArgs(1),
+	PersistentPreRunE: initRoot,
+	RunE:              runConfigConvert,
+}
+
+// runConfigConvert executes the config convert command.
+func runConfigConvert(cmd *cobra.Command, args []string) error {
+	root := rootFrom(cmd)
+	to, _ := cmd.Flags().GetString("to")
+	output, _ := cmd.Flags().GetString("output")
+
+	var format sevp.Format
+	switch {
+	case to != "":
+		var err error
+		if format, err = sevp.ParseFormat(to); err != nil {
+			return err
+		}
+	case output != "":
+		format = sevp.FormatOf(output)
+	default:
+		return errors.New("either --to or --output is required")
+	}
+
+	configPath := userConfigPath(cmd)
+	if len(args) == 1 {
+		configPath = args[0]
+	}
+
+	cmd.SilenceUsage = true
+
+	data, err := afero.ReadFile(root.Fs, filepath.Clean(configPath))
+	if err != nil {
+		return err
+	}
+
+	converted, err := sevp.ConvertConfig(data, sevp.FormatOf(configPath), format)
+	if err != nil {
+		return fmt.Errorf("%s: %w", configPath, err)
+	}
+
+	if output == "" {
+		_, err := cmd.OutOrStdout().Write(converted)
+		return err
+	}
+
+	if err := afero.WriteFile(root.Fs, filepath.Clean(output), converted, 0600); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "Converted %s to %s\n", configPath, output)
+	return nil
+}
@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/masamerc/sevp/pkg/sevp"
+)
+
+func init() {
+	configCmd.AddCommand(configAddCmd)
+	configCmd.AddCommand(configAddValueCmd)
+	configCmd.AddCommand(configRemoveCmd)
+	configCmd.AddCommand(configEditCmd)
+
+	configAddCmd.Flags().String("var", "", "the environment variable the selector sets")
+	configAddCmd.Flags().StringArray("value", nil, "a possible value of the selector, can be repeated")
+	configAddCmd.Flags().Bool("external", false, "read the values from the external config provider of the same name")
+}
+
+// configAddCmd adds a selector to the user config file.
+var configAddCmd = &cobra.Command{
+	Use:   "add <selector>",
+	Short: "Add a selector to the config",
+	Example: `  sevp config add node --var NODE_ENV --value development --value production
+  sevp config add aws --external`,
+	Args:              cobra.ExactArgs(1),
+	PersistentPreRunE: initRoot,
+	RunE:              runConfigAdd,
+}
+
+// configAddValueCmd adds values to a selector of the user config file.
+var configAddValueCmd = &cobra.Command{
+	Use:               "add-value <selector> <value>...",
+	Short:             "Add possible values to a selector of the config",
+	Args:              cobra.MinimumNArgs(2),
+	PersistentPreRunE: initRoot,
+	RunE:              runConfigAddValue,
+}
+
+// configRemoveCmd removes a selector from the user config file.
+var configRemoveCmd = &cobra.Command{
+	Use:     "remove <selector>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a selector from the config",
+	Long: `Remove a selector from the user config file, together with its subtables like [<selector>.meta].
+
+If the selector is the default selector, the default is removed as well.`,
+	Args:              cobra.ExactArgs(1),
+	PersistentPreRunE: initRoot,
+	RunE:              runConfigRemove,
+}
+
+// configEditCmd opens the user config file in the editor and validates it.
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit the config in $EDITOR and validate it",
+	Long: `Open the user config file in $VISUAL or $EDITOR (vi if neither is set).
+
+The changes are validated before they are saved. If there are problems, you can edit the config again or discard the changes.`,
+	Args:              cobra.NoArgs,
+	PersistentPreRunE: initRoot,
+	RunE:              runConfigEdit,
+}
+
+// runConfigAdd executes the config add command.
+func runConfigAdd(cmd *cobra.Command, args []string) error {
+	targetVar, _ := cmd.Flags().GetString("var")
+	values, _ := cmd.Flags().GetStringArray("value")
+	external, _ := cmd.Flags().GetBool("external")
+
+	if external && (targetVar != "" || len(values) > 0) {
+		return errors.New("--external can't be combined with --var or --value")
+	}
+
+	selector := &sevp.ConfigSelector{
+		Name:               args[0],
+		ReadExternalConfig: external,
+		TargetVar:          targetVar,
+		PossibleValues:     values,
+	}
+
+	return updateUserConfig(cmd, fmt.Sprintf("Added selector %s", args[0]), func(data []byte) ([]byte, error) {
+		return sevp.AddSelector(data, selector)
+	})
+}
+
+// runConfigAddValue executes the config add-value command.
+func runConfigAddValue(cmd *cobra.Command, args []string) error {
+	name, values := args[0], args[1:]
+
+	return updateUserConfig(cmd, fmt.Sprintf("Added %s to selector %s", strings.Join(values, ", "), name), func(data []byte) ([]byte, error) {
+		for _, value := range values {
+			var err error
+			if data, err = sevp.AddValue(data, name, value); err != nil {
+				return nil, err
+			}
+		}
+		return data, nil
+	})
+}
+
+// runConfigRemove executes the config remove command.
+func runConfigRemove(cmd *cobra.Command, args []string) error {
+	return updateUserConfig(cmd, fmt.Sprintf("Removed selector %s", args[0]), func(data []byte) ([]byte, error) {
+		return sevp.RemoveSelector(data, args[0])
+	})
+}
+
+// updateUserConfig applies the edit of the TOML document to the user config file and reports the change.
+func updateUserConfig(cmd *cobra.Command, message string, edit func(data []byte) ([]byte, error)) error {
+	configPath := userConfigPath(cmd)
+
+	cmd.SilenceUsage = true
+	if sevp.FormatOf(configPath) != sevp.FormatTOML {
+		return fmt.Errorf("%s: only TOML configs can be edited, convert it with: sevp config convert %s --to toml", configPath, configPath)
+	}
+
+	return writeUserConfig(cmd, configPath, message, edit)
+}
+
+// writeUserConfig applies the edit to the user config file and reports the change.
+func writeUserConfig(cmd *cobra.Command, configPath string, message string, edit func(data []byte) ([]byte, error)) error {
+	if err := sevp.UpdateConfigFile(rootFrom(cmd), configPath, edit); err != nil {
+		return fmt.Errorf("%s: %w", configPath, err)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "%s in %s\n", message, configPath)
+	return nil
+}
+
+// runConfigEdit executes the config edit command.
+//
+// The config is edited in a temporary file, so it is only saved once it is valid.
+func runConfigEdit(cmd *cobra.Command, args []string) error {
+	root := rootFrom(cmd)
+	configPath := userConfigPath(cmd)
+	cmd.SilenceUsage = true
+
+	original, err := afero.ReadFile(root.Fs, configPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	// keep the extension, so the editor highlights the format
+	tmp, err := os.CreateTemp("", "sevp-*"+filepath.Ext(configPath))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(original); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	stdin := bufio.NewReader(cmd.InOrStdin())
+
+	for {
+		if err := runEditor(cmd, tmp.Name()); err != nil {
+			return err
+		}
+
+		edited, err := os.ReadFile(tmp.Name())
+		if err != nil {
+			return err
+		}
+
+		if bytes.Equal(edited, original) {
+			fmt.Fprintln(cmd.OutOrStdout(), "No changes made")
+			return nil
+		}
+
+		problems := checkEditedConfig(edited, configPath)
+		if len(problems) == 0 {
+			return writeUserConfig(cmd, configPath, "Saved config", func([]byte) ([]byte, error) {
+				return edited, nil
+			})
+		}
+
+		for _, problem := range problems {
+			fmt.Fprintf(cmd.ErrOrStderr(), "error: %s\n", problem)
+		}
+
+		fmt.Fprint(cmd.ErrOrStderr(), "Edit again? [Y/n] ")
+		answer, _ := stdin.ReadString('\n')
+		if strings.EqualFold(strings.TrimSpace(answer), "n") {
+			return fmt.Errorf("changes discarded: %w", problemsFound(len(problems)))
+		}
+	}
+}
+
+// checkEditedConfig returns the problems of the edited config.
+func checkEditedConfig(data []byte, configPath string) []string {
+	cfg, err := sevp.ReadConfigFormat(bytes.NewReader(data), sevp.FormatOf(configPath))
+	if err != nil {
+		return []string{fmt.Sprintf("%s: %s", configPath, err)}
+	}
+
+	var problems []string
+	for _, diagnostic := range cfg.Validate() {
+		diagnostic.File = configPath
+		problems = append(problems, diagnostic.String())
+	}
+
+	return problems
+}
+
+// runEditor opens the file in the editor of the user and waits for it to exit.
+func runEditor(cmd *cobra.Command, path string) error {
+	editor := strings.TrimSpace(os.Getenv("VISUAL"))
+	if editor == "" {
+		editor = strings.TrimSpace(os.Getenv("EDITOR"))
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// the editor may come with arguments, e.g. "code --wait"
+	fields := strings.Fields(editor)
+	c := exec.Command(fields[0], append(fields[1:], path)...)
+	c.Stdin = os.Stdin
+	c.Stdout = cmd.OutOrStdout()
+	c.Stderr = cmd.ErrOrStderr()
+
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("editor %s failed: %w", editor, err)
+	}
+
+	return nil
+}
+
+// userConfigPath returns the path of the user config file the command edits.
+func userConfigPath(cmd *cobra.Command) string {
+	override, _ := cmd.Flags().GetString("config")
+	return sevp.ConfigPath(rootFrom(cmd), override)
+}
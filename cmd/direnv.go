@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/masamerc/sevp/pkg/sevp"
+)
+
+func init() {
+	direnvCmd.AddCommand(direnvStdlibCmd)
+	rootCmd.AddCommand(direnvCmd)
+}
+
+// direnvCmd prints the picked values for direnv.
+var direnvCmd = &cobra.Command{
+	Use:   "direnv",
+	Short: "Print the picked values in the .envrc format of direnv",
+	Long: `Print the picked values in the .envrc format of direnv, to be evaluated in an .envrc:
+
+    eval "$(sevp direnv)"
+
+or with the use_sevp function of "sevp direnv stdlib":
+
+    use sevp`,
+	Args: cobra.NoArgs,
+	RunE: runDirenv,
+}
+
+// direnvStdlibCmd prints the use_sevp function for direnv.
+var direnvStdlibCmd = &cobra.Command{
+	Use:   "stdlib",
+	Short: "Print the use_sevp function for the direnvrc of direnv",
+	Long: `Print the use_sevp function for direnv, to be added to ~/.config/direnv/direnvrc:
+
+    eval "$(sevp direnv stdlib)"
+
+Projects can then export the picked values with "use sevp" in their .envrc.
+The function watches the state file, including one set with state_file.`,
+	Args: cobra.NoArgs,
+	Run:  runDirenvStdlib,
+}
+
+// runDirenv executes the direnv command, printing the values of the state file as .envrc code.
+func runDirenv(cmd *cobra.Command, args []string) error {
+	envrc, err := sevp.Envrc(rootFrom(cmd))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(cmd.OutOrStdout(), envrc)
+	return nil
+}
+
+// runDirenvStdlib executes the direnv stdlib command, printing the use_sevp function.
+func runDirenvStdlib(cmd *cobra.Command, args []string) {
+	fmt.Fprintln(cmd.OutOrStdout(), sevp.DirenvStdlib(rootFrom(cmd)))
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/masamerc/sevp/app"
+	"github.com/masamerc/sevp/pkg/sevp"
+)
+
+func init() {
+	historyCmd.Flags().String("var", "", "only show changes of the environment variable")
+	historyCmd.Flags().String("source", "", "only show changes of the source, e.g. picker, directory or \"set <name>\"")
+	historyCmd.Flags().Duration("since", 0, "only show changes within the duration, e.g. 24h")
+	historyCmd.Flags().IntP("limit", "n", 20, "show at most this many of the latest changes, 0 for all")
+	undoCmd.Flags().BoolP("yes", "y", false, "restore values marked as dangerous without asking")
+	backCmd.Flags().BoolP("yes", "y", false, "restore a value marked as dangerous without asking")
+
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(undoCmd)
+	rootCmd.AddCommand(backCmd)
+}
+
+// historyCmd shows the history of picked values.
+var historyCmd = &cobra.Command{
+	Use:   "history [selector]",
+	Short: "Show the history of picked values",
+	Long: `Show the history of picked values, from the oldest to the newest change.
+
+Every change of a value is recorded with its time, selector, old and new value and how it was made.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runHistory,
+}
+
+// undoCmd reverts the latest change.
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Revert the latest change of picked values",
+	Long: `Revert the latest change of picked values, e.g. all values of an applied set.
+
+Undoing repeatedly walks back through the history.
+Values marked as dangerous in the config are only restored with --yes.`,
+	Args: cobra.NoArgs,
+	RunE: runUndo,
+}
+
+// backCmd restores the previous value of a selector.
+var backCmd = &cobra.Command{
+	Use:   "back <selector>",
+	Short: "Restore the previous value of a selector",
+	Long: `Restore the value the selector had before its latest change.
+
+Going back twice returns to the value before going back, just like cd -.
+Values marked as dangerous in the config are only restored with --yes.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBack,
+}
+
+// runHistory executes the history command, printing the filtered history.
+func runHistory(cmd *cobra.Command, args []string) error {
+	history, err := sevp.ReadHistory(rootFrom(cmd))
+	if err != nil {
+		return err
+	}
+
+	var filter sevp.HistoryFilter
+	if len(args) == 1 {
+		filter.Selector = args[0]
+	}
+	filter.Var, _ = cmd.Flags().GetString("var")
+	filter.Source, _ = cmd.Flags().GetString("source")
+	if since, _ := cmd.Flags().GetDuration("since"); since > 0 {
+		filter.Since = time.Now().Add(-since)
+	}
+
+	var entries []sevp.HistoryEntry
+	for _, entry := range history {
+		if filter.Match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	if limit, _ := cmd.Flags().GetInt("limit"); limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	if len(entries) == 0 {
+		fmt.Fprintln(cmd.ErrOrStderr(), "No changes found.")
+		return nil
+	}
+
+	// Styling
+	styles := stylesFrom(cmd)
+	accentStyle := styles.Rendering.TargetType
+	faintStyle := styles.Rendering.Tag
+
+	for _, entry := range entries {
+		selector := entry.Selector
+		if selector == "" {
+			selector = "-"
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "%s  %-12s %s  %s  %s\n",
+			entry.Time.Local().Format(time.DateTime),
+			selector,
+			accentStyle.Render(entry.Var),
+			formatChange(styles, entry),
+			faintStyle.Render("("+entry.Source+")"),
+		)
+	}
+
+	return nil
+}
+
+// runUndo executes the undo command, reverting the latest change.
+func runUndo(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	styles := stylesFrom(cmd)
+
+	reverted, err := sevp.Undo(rootFrom(cmd), checkRestores(cmd))
+	if err != nil {
+		return err
+	}
+
+	lines := make([]string, 0, len(reverted))
+	for _, entry := range reverted {
+		lines = append(lines, formatRevert(cmd, styles, entry))
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), strings.Join(lines, "\n"))
+
+	return nil
+}
+
+// runBack executes the back command, restoring the previous value of the selector.
+func runBack(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	styles := stylesFrom(cmd)
+
+	reverted, err := sevp.Back(rootFrom(cmd), args[0], checkRestores(cmd))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), formatRevert(cmd, styles, reverted))
+	return nil
+}
+
+// checkRestores returns a check of reverted changes that refuses to restore dangerous values, unless the yes flag confirms them.
+func checkRestores(cmd *cobra.Command) sevp.RevertCheck {
+	return func(changes []sevp.HistoryEntry) error {
+		return confirmDangerous(cmd, configFrom(cmd).Restores(changes))
+	}
+}
+
+// formatChange formats the old and new value of a history entry with the styles.
+func formatChange(styles *app.StyleSet, entry sevp.HistoryEntry) string {
+	highlightStyle := styles.Rendering.SelectedResult
+
+	value := func(v *string) string {
+		if v == nil {
+			return "(unset)"
+		}
+		return *v
+	}
+
+	return fmt.Sprintf("%s -> %s", value(entry.Old), highlightStyle.Render(value(entry.New)))
+}
+
+// formatRevert formats a reverted history entry with the styles, warning about variables that must be unset in the shell.
+func formatRevert(cmd *cobra.Command, styles *app.StyleSet, entry sevp.HistoryEntry) string {
+	accentStyle := styles.Rendering.TargetType
+
+	if entry.Old == nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s was not set before, run 'unset %s' to remove it from the current shell\n", entry.Var, entry.Var)
+	}
+
+	return fmt.Sprintf("%s: %s", accentStyle.Render(entry.Var), formatChange(styles, sevp.HistoryEntry{Old: entry.New, New: entry.Old}))
+}
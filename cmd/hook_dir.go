@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/masamerc/sevp/pkg/sevp"
+)
+
+func init() {
+	rootCmd.AddCommand(hookDirCmd)
+}
+
+// hookDirCmd applies the directory values of the working directory. It is run by the shell hook on every directory change.
+var hookDirCmd = &cobra.Command{
+	Use:   "hook-dir",
+	Short: "Apply the directory values of the working directory, run by the shell hook",
+	Long: `Apply the directory values of the working directory, declared in the values table of project configs or in .sevp-values files.
+
+Values are only applied once the project files are allowed with "sevp allow", and values marked as dangerous are never applied. The values of the directory that was left are restored. Variables to unset are printed as shell code for the hook to evaluate,
+all others are written to the state file.`,
+	Hidden:            true,
+	Args:              cobra.NoArgs,
+	PersistentPreRunE: initRoot,
+	RunE:              runHookDir,
+}
+
+// runHookDir executes the hook-dir command, switching to the directory values of the working directory.
+func runHookDir(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+	root := rootFrom(cmd)
+
+	// the config is loaded everywhere, so the values are written to the same state file as by every other command
+	override, _ := cmd.Flags().GetString("config")
+	configPath := sevp.ConfigPath(root, override)
+
+	cfg, err := sevp.InitConfig(root, configPath)
+	if errors.Is(err, sevp.ErrDefaultConfigCreated) {
+		// the hook runs silently, so the created default config is loaded right away
+		cfg, err = sevp.InitConfig(root, configPath)
+	}
+	if err != nil {
+		return err
+	}
+	applyOptions(root, cfg)
+
+	projectPaths, err := sevp.FindProjectConfigs(root, root.Workdir)
+	if err != nil {
+		return err
+	}
+
+	var assignments []sevp.Assignment
+	if len(projectPaths) > 0 {
+		blocked, err := sevp.BlockedProjectFiles(root, projectPaths)
+		if err != nil {
+			return err
+		}
+
+		// values of project files that are not allowed are not applied, as if the directory was left
+		if len(cfg.DirValues()) > 0 && len(blocked) > 0 {
+			for _, path := range blocked {
+				fmt.Fprintf(cmd.ErrOrStderr(), "sevp: %s is blocked, review it and run 'sevp allow' to apply its directory values\n", path)
+			}
+		} else if assignments, err = cfg.ResolveDirValues(root); err != nil {
+			return err
+		}
+
+		// the hook can't ask for confirmation, so dangerous values are never applied on a directory change
+		safe := assignments[:0]
+		for _, assignment := range assignments {
+			if assignment.Dangerous {
+				fmt.Fprintf(cmd.ErrOrStderr(), "sevp: %s=%s (%s) is marked as dangerous, pick it with 'sevp set %s %s --yes'\n",
+					assignment.TargetVar, assignment.Value, assignment.Selector, assignment.Selector, assignment.Item)
+				continue
+			}
+			safe = append(safe, assignment)
+		}
+		assignments = safe
+	}
+
+	result, err := sevp.SwitchDirValues(root, assignments)
+	if err != nil {
+		return err
+	}
+
+	for _, assignment := range result.Applied {
+		fmt.Fprintf(cmd.ErrOrStderr(), "sevp: %s=%s (%s)\n", assignment.TargetVar, assignment.Value, assignment.Selector)
+	}
+
+	restored := make([]string, 0, len(result.Restored))
+	for target := range result.Restored {
+		restored = append(restored, target)
+	}
+	sort.Strings(restored)
+	for _, target := range restored {
+		fmt.Fprintf(cmd.ErrOrStderr(), "sevp: %s=%s (restored)\n", target, result.Restored[target])
+	}
+
+	for _, target := range result.Unset {
+		fmt.Fprintf(cmd.ErrOrStderr(), "sevp: %s unset\n", target)
+		fmt.Fprintf(cmd.OutOrStdout(), "unset %s\n", target)
+	}
+
+	return nil
+}
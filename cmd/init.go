@@ -5,7 +5,7 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"github.com/masamerc/sevp/internal"
+	"github.com/masamerc/sevp/pkg/sevp"
 )
 
 func init() {
@@ -15,19 +15,19 @@ func init() {
 // initCmd prints out a shell hook for the supported shells.
 var initCmd = &cobra.Command{
 	Use:       "init <shell>",
-	Short:     fmt.Sprintf("Prints out a shell hook for the specified shell. Supported shells: %v", internal.SupportedShells),
-	ValidArgs: internal.SupportedShells,
+	Short:     fmt.Sprintf("Prints out a shell hook for the specified shell. Supported shells: %v", sevp.SupportedShells),
+	ValidArgs: sevp.SupportedShells,
 	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
-	Run:       runInit,
-}
-
-// shellToHook maps the shell name to the corresponding hook function.
-var shellToHook = map[string]string{
-	"bash": internal.BashHook,
-	"zsh":  internal.ZshHook,
+	RunE:      runInit,
 }
 
 // runInit executes the init command, printing the shell hook for the specified shell.
-func runInit(cmd *cobra.Command, args []string) {
-	fmt.Fprintln(cmd.OutOrStdout(), shellToHook[args[0]])
+func runInit(cmd *cobra.Command, args []string) error {
+	hook, err := sevp.Hook(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), hook)
+	return nil
 }
@@ -4,12 +4,11 @@ import (
 	"fmt"
 	"os"
 	"sort"
+	"time"
 
-	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
 
-	"github.com/masamerc/sevp/app"
-	"github.com/masamerc/sevp/internal"
+	"github.com/masamerc/sevp/pkg/sevp"
 )
 
 func init() {
@@ -29,12 +28,19 @@ var listCmd = &cobra.Command{
 
 // runList executes the list command, printing all available selectors.
 func runList(cmd *cobra.Command, args []string) {
-	selectorMap, err := internal.ParseSelectorsFromConfig()
+	selectorMap, err := configFrom(cmd).ParseSelectors()
 	if err != nil {
 		fmt.Fprintf(cmd.OutOrStderr(), "Error getting selectors: %v\n", err)
 		return
 	}
 
+	// the environment of this process is stale once a value is picked, the state is not
+	state, err := sevp.LoadState(rootFrom(cmd))
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStderr(), "Error reading state: %v\n", err)
+		return
+	}
+
 	var selectorSlice []string
 
 	for s := range selectorMap {
@@ -54,8 +60,10 @@ func runList(cmd *cobra.Command, args []string) {
 	}
 
 	// Styling
-	purpleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(app.HexBrightPurple))
-	greenStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(app.HexBrightGreen))
+	styles := stylesFrom(cmd).Rendering
+	accentStyle := styles.TargetType
+	highlightStyle := styles.SelectedResult
+	faintStyle := styles.Tag
 
 	// Calculate max width for padding;w
 	maxWidth := 0
@@ -68,17 +76,43 @@ func runList(cmd *cobra.Command, args []string) {
 
 	for _, s := range sorted {
 		currentSelector := selectorMap[s]
-		currentTargetVar := currentSelector.TargetVar
-		currentValue := os.Getenv(currentTargetVar)
+		entry, picked := currentEntry(state, s, currentSelector.TargetVar)
+		currentTargetVar := entry.Var
+		currentValue := entry.Value
 
 		paddedName := fmt.Sprintf("%-*s", maxWidth, s) // left-aligned to width
-		// nameStyled := purpleStyle.Render(paddedName)
+		// nameStyled := accentStyle.Render(paddedName)
 		currentStyled := fmt.Sprintf(
 			"(current: %v = %v)",
-			purpleStyle.Render(currentTargetVar),
-			greenStyle.Render(currentValue),
+			accentStyle.Render(currentTargetVar),
+			highlightStyle.Render(currentValue),
 		)
 
+		if picked && entry.Source != sevp.SourceUnknown {
+			currentStyled += faintStyle.Render(fmt.Sprintf(" %s, %s", entry.Source, entry.Time.Local().Format(time.DateTime)))
+		}
+
 		fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", paddedName, currentStyled)
 	}
 }
+
+// currentEntry returns the state entry of the selector with the target variable, and whether it is in the state.
+//
+// External selectors have no target variable in the config, so their entry is found by the selector instead.
+// Variables sevp didn't set are read from the environment.
+func currentEntry(state *sevp.State, selector string, targetVar string) (sevp.StateEntry, bool) {
+	if targetVar == "" {
+		for _, entry := range state.Entries {
+			if entry.Selector == selector {
+				return entry, true
+			}
+		}
+		return sevp.StateEntry{}, false
+	}
+
+	if entry, ok := state.Entry(targetVar); ok {
+		return entry, true
+	}
+
+	return sevp.StateEntry{Var: targetVar, Value: os.Getenv(targetVar)}, false
+}
@@ -1,13 +1,18 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"slices"
 
 	"github.com/spf13/cobra"
 
 	"github.com/masamerc/sevp/app"
 	"github.com/masamerc/sevp/internal"
+	"github.com/masamerc/sevp/pkg/sevp"
 )
 
 // rootCmd represents the base command
@@ -19,54 +24,255 @@ var rootCmd = &cobra.Command{
 		cobra.MinimumNArgs(0),
 		cobra.MaximumNArgs(1),
 	),
-	RunE: runRoot,
+	PersistentPreRunE: loadConfig,
+	RunE:              runRoot,
 }
 
 // runRoot acts as the main entry point for the entire CLI application.
+//
+// It picks a value of the given or default selector, or starts at the selector list
+// if there is no valid default selector or the all flag is set.
 func runRoot(cmd *cobra.Command, args []string) error {
-	selector, err := internal.GetSelector(args)
+	root := rootFrom(cmd)
+	cfg := configFrom(cmd)
+
+	state, err := sevp.LoadState(root)
 	if err != nil {
 		return err
 	}
 
-	targetVar, possibleValues, err := selector.Read()
+	styles := stylesFrom(cmd)
+	keys := keysFrom(cmd)
+
+	all, _ := cmd.Flags().GetBool("all")
+	if all && len(args) == 1 {
+		return errors.New("--all starts at the selector list and takes no selector")
+	}
+
+	if len(args) == 1 {
+		return runPicker(root, cfg, state, styles, keys, args[0])
+	}
+
+	if !all {
+		_, err := cfg.FromConfig(cfg.Default)
+		if err == nil {
+			return runPicker(root, cfg, state, styles, keys, cfg.Default)
+		}
+		slog.Debug("No valid default selector, starting at the selector list", "default", cfg.Default, "err", err)
+	}
+
+	names := cfg.Names()
+	if len(names) == 0 {
+		return errors.New("no selectors found in the config")
+	}
+
+	selectors := make([]app.SelectorItem, 0, len(names))
+	for _, name := range names {
+		var targetVar string
+		if section, err := cfg.FromConfig(name); err == nil {
+			targetVar = section.TargetVar
+		}
+
+		entry, _ := currentEntry(state, name, targetVar)
+		selectors = append(selectors, app.SelectorItem{Name: name, TargetVar: entry.Var, Current: entry.Value})
+	}
+
+	return app.NewChooser(selectors, func(selector string) (*app.App, error) {
+		return newPicker(root, cfg, state, styles, keys, selector)
+	}).WithStyles(styles).WithKeys(keys).Run()
+}
+
+// runPicker runs the value list of the selector.
+func runPicker(root *sevp.Root, cfg *sevp.Config, state *sevp.State, styles *app.StyleSet, keys app.KeyMap, selectorName string) error {
+	picker, err := newPicker(root, cfg, state, styles, keys, selectorName)
 	if err != nil {
 		return err
 	}
 
-	app := app.NewApp(possibleValues, targetVar)
+	return picker.Run()
+}
 
-	if err := app.Run(); err != nil {
-		return err
+// newPicker returns the app picking a value of the selector, ranked by usage and starting on its current value.
+func newPicker(root *sevp.Root, cfg *sevp.Config, state *sevp.State, styles *app.StyleSet, keys app.KeyMap, selectorName string) (*app.App, error) {
+	selector, err := cfg.GetSelector(root, []string{selectorName})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	targetVar, possibleValues, err := selector.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	// GetSelector succeeded, so the selector is in the config
+	section, _ := cfg.FromConfig(selectorName)
+
+	entry, picked := currentEntry(state, selectorName, targetVar)
+
+	// the value is compared against the state, which shells evaluate, falling back to the environment
+	var current *string
+	if picked {
+		current = &entry.Value
+	} else if value, ok := os.LookupEnv(targetVar); ok {
+		current = &value
+	}
+
+	render := func(item string) (string, error) {
+		return sevp.RenderValue(selector, item)
+	}
+
+	return app.NewApp(possibleValues, targetVar, app.WriteValue(root, selectorName, targetVar, render, styles)).
+		WithStyles(styles).
+		WithKeys(keys).
+		WithUnset(app.UnsetValue(root, targetVar, styles)).
+		WithCopy(render).
+		WithFavorites(app.SelectorFavorites(root, selectorName)).
+		WithCurrent(currentItem(entry, possibleValues)).
+		WithDanger(section.IsDangerous, section.DangerousColor).
+		WithPreview(func(item string) (app.Preview, error) {
+			value, err := render(item)
+			if err != nil {
+				return app.Preview{}, err
+			}
+
+			metadata, err := sevp.ItemMetadata(selector, item)
+			if err != nil {
+				return app.Preview{}, err
+			}
+
+			return app.Preview{
+				Metadata: metadata,
+				Changes:  []app.Change{{Var: targetVar, Old: current, New: value}},
+			}, nil
+		}), nil
+}
+
+// currentItem returns the item the current value of the entry was picked from,
+// or the value itself if its item is unknown, e.g. for values read from the environment.
+func currentItem(entry sevp.StateEntry, items []string) string {
+	if entry.Item != "" && slices.Contains(items, entry.Item) {
+		return entry.Item
+	}
+
+	return entry.Value
 }
 
 // Execute is the main entry point for the CLI application.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
+		// The default config was just created, exit so the CLI can pick up the new config
+		if errors.Is(err, sevp.ErrDefaultConfigCreated) {
+			return
+		}
+
 		os.Exit(1)
 	}
 }
 
-// init initializes the CLI application by setting up the configuration.
+// init sets up the flags shared by all commands.
 func init() {
-	cobra.OnInitialize(func() {
-		internal.InitLogger()
-
-		if err := internal.InitConfig(); err != nil {
-			// If config is not found, create one with the default config content
-			// and exit so the CLI can pick up the new config
-			if err.Error() == "created default config" {
-				fmt.Fprintln(os.Stderr, "Created default config: $HOME/.config/sevp.toml.")
-				fmt.Fprintln(os.Stderr, "Try running sevp again or edit the config to your needs.")
-				os.Exit(0)
-			}
+	rootCmd.PersistentFlags().StringP("config", "c", "", "path to the user config file in TOML, YAML or JSON (default $SEVP_CONFIG or $XDG_CONFIG_HOME/sevp.toml)")
+	rootCmd.Flags().BoolP("all", "a", false, "always start at the selector list instead of the default selector")
+	rootCmd.PersistentFlags().String("root", "", "run in a sandbox directory that replaces the home directory (default $SEVP_ROOT)")
+}
+
+type (
+	// configKey is the context key under which the loaded config is stored.
+	configKey struct{}
+
+	// rootKey is the context key under which the root is stored.
+	rootKey struct{}
+)
 
-			// For other errors just exit
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+// loadConfig initializes the logger, the root and the configuration before any command runs.
+// The root and the loaded config are stored in the command's context, see rootFrom and configFrom.
+func loadConfig(cmd *cobra.Command, args []string) error {
+	if err := initRoot(cmd, args); err != nil {
+		return err
+	}
+	root := rootFrom(cmd)
+
+	override, _ := cmd.Flags().GetString("config")
+	configPath := sevp.ConfigPath(root, override)
+
+	cfg, err := sevp.InitConfig(root, configPath)
+	if err != nil {
+		// If config is not found, one is created with the default config content
+		if errors.Is(err, sevp.ErrDefaultConfigCreated) {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			fmt.Fprintf(cmd.ErrOrStderr(), "Created default config: %s.\n", configPath)
+			fmt.Fprintln(cmd.ErrOrStderr(), "Try running sevp again or edit the config to your needs.")
 		}
-	})
+		return err
+	}
+	applyOptions(root, cfg)
+
+	cmd.SetContext(context.WithValue(cmd.Context(), configKey{}, cfg))
+	return nil
+}
+
+// applyOptions applies the options of the config that change the root.
+func applyOptions(root *sevp.Root, cfg *sevp.Config) {
+	if cfg.Options.StateFile != "" {
+		root.StatePath = cfg.Options.StateFile
+	}
+}
+
+// initRoot initializes the logger and the root without loading the configuration.
+// The root is stored in the command's context, see rootFrom.
+func initRoot(cmd *cobra.Command, args []string) error {
+	internal.InitLogger()
+
+	root, err := newRoot(cmd)
+	if err != nil {
+		return err
+	}
+
+	cmd.SetContext(context.WithValue(cmd.Context(), rootKey{}, root))
+	return nil
+}
+
+// newRoot returns the sandbox root if one is set with --root or SEVP_ROOT, or the OS root otherwise.
+func newRoot(cmd *cobra.Command) (*sevp.Root, error) {
+	sandbox, _ := cmd.Flags().GetString("root")
+	if sandbox == "" {
+		sandbox = os.Getenv("SEVP_ROOT")
+	}
+
+	if sandbox != "" {
+		return sevp.SandboxRoot(sandbox), nil
+	}
+
+	return sevp.OSRoot()
+}
+
+// rootFrom returns the root the command operates in.
+func rootFrom(cmd *cobra.Command) *sevp.Root {
+	return cmd.Context().Value(rootKey{}).(*sevp.Root)
+}
+
+// configFrom returns the config loaded for the command.
+func configFrom(cmd *cobra.Command) *sevp.Config {
+	return cmd.Context().Value(configKey{}).(*sevp.Config)
+}
+
+// stylesFrom returns the styles of the theme of the config loaded for the command,
+// or of the default theme for commands that don't load the config.
+func stylesFrom(cmd *cobra.Command) *app.StyleSet {
+	if cfg, ok := cmd.Context().Value(configKey{}).(*sevp.Config); ok {
+		return app.NewStyleSet(app.ThemePalette(cfg.Theme))
+	}
+
+	return app.DefaultStyleSet()
+}
+
+// keysFrom returns the key bindings of the config loaded for the command,
+// or the default key bindings for commands that don't load the config.
+func keysFrom(cmd *cobra.Command) app.KeyMap {
+	if cfg, ok := cmd.Context().Value(configKey{}).(*sevp.Config); ok {
+		return app.NewKeyMap(cfg.Keys)
+	}
+
+	return app.DefaultKeyMap()
 }
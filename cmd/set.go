@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/masamerc/sevp/app"
+	"github.com/masamerc/sevp/pkg/sevp"
+)
+
+func init() {
+	setCmd.Flags().BoolP("yes", "y", false, "pick values marked as dangerous without asking")
+	useCmd.Flags().BoolP("yes", "y", false, "apply sets with values marked as dangerous without asking")
+
+	rootCmd.AddCommand(setCmd)
+}
+
+// setCmd picks a value of a selector without the TUI.
+var setCmd = &cobra.Command{
+	Use:   "set <selector> <item>",
+	Short: "Pick a value of a selector without the TUI",
+	Long: `Pick a value of a selector without the TUI, e.g. in scripts.
+
+The item is checked against the possible values of the selector before anything is written.
+Values marked as dangerous in the config are only picked with --yes.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runSet,
+}
+
+// runSet executes the set command, writing the value of the item picked from the selector.
+func runSet(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	root := rootFrom(cmd)
+
+	assignment, err := configFrom(cmd).Resolve(root, args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	if err := confirmDangerous(cmd, []sevp.Assignment{assignment}); err != nil {
+		return err
+	}
+
+	apply := app.WriteValue(root, assignment.Selector, assignment.TargetVar, func(item string) (string, error) {
+		return assignment.Value, nil
+	}, stylesFrom(cmd))
+
+	result, err := apply(assignment.Item)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), result)
+	return nil
+}
+
+// confirmDangerous returns an error if any of the assignments is dangerous, unless the yes flag confirms them.
+func confirmDangerous(cmd *cobra.Command, assignments []sevp.Assignment) error {
+	if yes, _ := cmd.Flags().GetBool("yes"); yes {
+		return nil
+	}
+
+	var dangerous []string
+	for _, assignment := range assignments {
+		if assignment.Dangerous {
+			dangerous = append(dangerous, assignment.Selector+"="+assignment.Item)
+		}
+	}
+
+	if len(dangerous) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%s marked as dangerous, confirm with --yes", strings.Join(dangerous, ", "))
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/masamerc/sevp/app"
+	"github.com/masamerc/sevp/pkg/sevp"
+)
+
+func init() {
+	rootCmd.AddCommand(useCmd)
+}
+
+// useCmd applies a set of selectors at once.
+var useCmd = &cobra.Command{
+	Use:   "use [set]",
+	Short: "Switch several selectors at once with a set",
+	Long: `Switch several selectors at once with a set defined in the sets table of the config.
+
+All items of the set are checked before anything is written, so the set is either applied completely or not at all.
+Without arguments, the set is picked interactively.
+
+Sets with values marked as dangerous in the config are only applied with --yes, or after confirming them in the picker.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runUse,
+}
+
+// runUse executes the use command, applying the given or picked set.
+func runUse(cmd *cobra.Command, args []string) error {
+	cfg := configFrom(cmd)
+	root := rootFrom(cmd)
+	styles := stylesFrom(cmd)
+
+	apply := func(name string) (string, error) {
+		assignments, err := cfg.ApplySet(root, name)
+		if err != nil {
+			return "", err
+		}
+		return formatAssignments(styles, name, assignments), nil
+	}
+
+	if len(args) == 1 {
+		cmd.SilenceUsage = true
+
+		assignments, err := cfg.ResolveSet(root, args[0])
+		if err != nil {
+			return err
+		}
+		if err := confirmDangerous(cmd, assignments); err != nil {
+			return err
+		}
+
+		result, err := apply(args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(cmd.OutOrStdout(), result)
+		return nil
+	}
+
+	names := cfg.SetNames()
+	if len(names) == 0 {
+		return errors.New("no sets defined, add them to the sets table of the config")
+	}
+
+	// sets are dangerous if they pick any dangerous value, sets that don't resolve fail once they are applied
+	dangerous := make(map[string]bool)
+	for _, name := range names {
+		if assignments, err := cfg.ResolveSet(root, name); err == nil {
+			dangerous[name] = slices.ContainsFunc(assignments, func(a sevp.Assignment) bool { return a.Dangerous })
+		}
+	}
+
+	return app.NewApp(names, "sets", apply).
+		WithStyles(styles).
+		WithKeys(keysFrom(cmd)).
+		WithDanger(func(name string) bool { return dangerous[name] }, "").
+		Run()
+}
+
+// formatAssignments formats the assignments of the applied set for display with the styles.
+func formatAssignments(styles *app.StyleSet, name string, assignments []sevp.Assignment) string {
+	// Styling
+	accentStyle := styles.Rendering.TargetType
+	highlightStyle := styles.Rendering.SelectedResult
+
+	sort.Slice(assignments, func(i, j int) bool {
+		return assignments[i].TargetVar < assignments[j].TargetVar
+	})
+
+	lines := []string{fmt.Sprintf("%s applied:", highlightStyle.Render(name))}
+	for _, assignment := range assignments {
+		value := assignment.Item
+		if assignment.Value != assignment.Item {
+			value += " -> " + assignment.Value
+		}
+		lines = append(lines, fmt.Sprintf("  %s=%s", accentStyle.Render(assignment.TargetVar), value))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -3,11 +3,9 @@ package cmd
 import (
 	"fmt"
 
-	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
 
-	"github.com/masamerc/sevp/app"
-	"github.com/masamerc/sevp/internal"
+	"github.com/masamerc/sevp/pkg/sevp"
 )
 
 func init() {
@@ -24,31 +22,10 @@ var viewCmd = &cobra.Command{
 
 // runView executes the view command, displaying the details of a selector.
 func runView(cmd *cobra.Command, args []string) {
-	var selector internal.Selector
-
-	// Config found
-	selectorMap, err := internal.ParseSelectorsFromConfig()
+	// External config selectors read and parse their external config file.
+	selector, err := configFrom(cmd).GetSelector(rootFrom(cmd), args)
 	if err != nil {
-		fmt.Fprintf(cmd.OutOrStderr(), "Error getting selectors: %v\n", err)
-		return
-	}
-
-	selectorChoice := args[0]
-
-	if selectorChosen, ok := selectorMap[selectorChoice]; ok {
-		// If the selector is a external config selector,
-		// sevp will attempt to read and parse the external config file.
-		if selectorChosen.ReadExternalConfig {
-			selector, err = selectorChosen.IntoExternalConfigSelector()
-			if err != nil {
-				fmt.Fprintf(cmd.OutOrStderr(), "Failed to parse selectors: %v\n", err)
-				return
-			}
-		} else {
-			selector = selectorChosen
-		}
-	} else {
-		fmt.Fprintf(cmd.OutOrStderr(), "Selector not found: %v\n", err)
+		fmt.Fprintf(cmd.OutOrStderr(), "Failed to get selector: %v\n", err)
 		return
 	}
 
@@ -60,14 +37,24 @@ func runView(cmd *cobra.Command, args []string) {
 	}
 
 	// Some styling for the stdout
-	purpleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(app.HexBrightPurple))
-	greenStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(app.HexBrightGreen))
+	styles := stylesFrom(cmd).Rendering
+	accentStyle := styles.TargetType
+	highlightStyle := styles.SelectedResult
 
 	// Display
-	fmt.Fprintf(cmd.OutOrStdout(), "\ntarget environment variable:\n  %s\n", purpleStyle.Render(targetVar))
+	fmt.Fprintf(cmd.OutOrStdout(), "\ntarget environment variable:\n  %s\n", accentStyle.Render(targetVar))
 	fmt.Fprintf(cmd.OutOrStdout(), "\npossible values:\n")
 
 	for _, v := range possibleValues {
-		fmt.Fprintf(cmd.OutOrStdout(), "  - %s\n", greenStyle.Render(v))
+		// with a value template, show the value written for the item as well
+		rendered, err := sevp.RenderValue(selector, v)
+		switch {
+		case err != nil:
+			fmt.Fprintf(cmd.OutOrStdout(), "  - %s -> error: %v\n", highlightStyle.Render(v), err)
+		case rendered != v:
+			fmt.Fprintf(cmd.OutOrStdout(), "  - %s -> %s\n", highlightStyle.Render(v), rendered)
+		default:
+			fmt.Fprintf(cmd.OutOrStdout(), "  - %s\n", highlightStyle.Render(v))
+		}
 	}
 }
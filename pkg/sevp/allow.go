@@ -0,0 +1,131 @@
+package sevp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+)
+
+// Directory values are only applied from project files the user allowed, just like direnv only loads allowed .envrc files.
+// A file is allowed with its content, so any change to it blocks its values again until it is allowed again.
+
+// AllowFileName is the name of the file in the user's home directory that records the allowed project files.
+const AllowFileName = ".sevp-allow"
+
+// AllowFile returns the path of the allow file in the home directory of the root.
+func AllowFile(root *Root) string {
+	return root.HomePath(AllowFileName)
+}
+
+// AllowProjectFiles allows the directory values of the project files in their current content, see FindProjectConfigs.
+func AllowProjectFiles(root *Root, paths []string) error {
+	return updateAllowed(root, func(allowed map[string]string) error {
+		for _, path := range paths {
+			hash, err := hashFile(root.Fs, path)
+			if err != nil {
+				return err
+			}
+			allowed[filepath.Clean(path)] = hash
+		}
+		return nil
+	})
+}
+
+// DenyProjectFiles revokes the allowance of the project files, blocking their directory values.
+func DenyProjectFiles(root *Root, paths []string) error {
+	return updateAllowed(root, func(allowed map[string]string) error {
+		for _, path := range paths {
+			delete(allowed, filepath.Clean(path))
+		}
+		return nil
+	})
+}
+
+// BlockedProjectFiles returns the project files that are not allowed in their current content, in the order of the paths.
+func BlockedProjectFiles(root *Root, paths []string) ([]string, error) {
+	allowed, err := readAllowed(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var blocked []string
+	for _, path := range paths {
+		hash, err := hashFile(root.Fs, path)
+		if err != nil {
+			return nil, err
+		}
+		if allowed[filepath.Clean(path)] != hash {
+			blocked = append(blocked, path)
+		}
+	}
+
+	return blocked, nil
+}
+
+// hashFile returns the hex encoded SHA-256 hash of the content of the file.
+func hashFile(fs afero.Fs, path string) (string, error) {
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// readAllowed reads the allow file of the root, mapping the paths of allowed files to the hashes of their content.
+// A missing file allows nothing.
+func readAllowed(root *Root) (map[string]string, error) {
+	allowed := make(map[string]string)
+
+	data, err := afero.ReadFile(root.Fs, AllowFile(root))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return allowed, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &allowed); err != nil {
+		// a broken allow file blocks all values, which are allowed again with sevp allow
+		slog.Warn("Ignoring invalid allow file", "path", AllowFile(root), "err", err)
+		return make(map[string]string), nil
+	}
+
+	return allowed, nil
+}
+
+// updateAllowed applies the update to the allowed files of the root under a lock, and writes them atomically.
+func updateAllowed(root *Root, update func(allowed map[string]string) error) error {
+	path := AllowFile(root)
+
+	unlock, err := lockFile(root.Fs, path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	allowed, err := readAllowed(root)
+	if err != nil {
+		return err
+	}
+
+	if err := update(allowed); err != nil {
+		return fmt.Errorf("updating allowed project files: %w", err)
+	}
+
+	// maps are written with sorted keys, so the file only changes with the allowed files
+	data, err := json.MarshalIndent(allowed, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(root.Fs, path, append(data, '\n'), 0600)
+}
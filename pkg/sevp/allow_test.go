@@ -0,0 +1,38 @@
+package sevp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Project files should be blocked until they are allowed, and again once they change or are denied
+func TestAllowProjectFiles(t *testing.T) {
+	root := newTestRoot()
+	paths := []string{"/home/test/repo/.sevp.toml", "/home/test/repo/prod/.sevp-values"}
+	writeTestFiles(t, root, map[string]string{
+		paths[0]: "[values]\nkube = \"dev\"\n",
+		paths[1]: "kube = \"prod\"\n",
+	})
+
+	blocked, err := BlockedProjectFiles(root, paths)
+	require.NoError(t, err)
+	assert.Equal(t, paths, blocked, "project files are blocked by default")
+
+	require.NoError(t, AllowProjectFiles(root, paths))
+	blocked, err = BlockedProjectFiles(root, paths)
+	require.NoError(t, err)
+	assert.Empty(t, blocked)
+
+	writeTestFiles(t, root, map[string]string{paths[1]: "kube = \"staging\"\n"})
+	blocked, err = BlockedProjectFiles(root, paths)
+	require.NoError(t, err)
+	assert.Equal(t, paths[1:], blocked, "changed files are blocked again")
+
+	require.NoError(t, AllowProjectFiles(root, paths[1:]))
+	require.NoError(t, DenyProjectFiles(root, paths[:1]))
+	blocked, err = BlockedProjectFiles(root, paths)
+	require.NoError(t, err)
+	assert.Equal(t, paths[:1], blocked)
+}
@@ -0,0 +1,565 @@
+package sevp
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/afero"
+)
+
+//go:embed default_config.toml
+var defaultConfig string
+
+// ErrDefaultConfigCreated is returned by InitConfig when no config file was found
+// and a default one was created in its place.
+var ErrDefaultConfigCreated = errors.New("created default config")
+
+// reservedKeys are the top-level keys of a config that are not selectors.
+var reservedKeys = []string{"default", "include", "options", "theme", "keys", "sets", "values"}
+
+// errEmptyConfig is returned by ReadConfig when the config does not define anything.
+var errEmptyConfig = errors.New("config file is empty")
+
+// Selector is an interface that defines a method for reading configuration values.
+type Selector interface {
+	Read() (string, []string, error)
+}
+
+// ConfigSelector is a struct that defines a set of custom configuration options for a selector.
+//
+// The description tags document the keys in the JSON schema of the config, see JSONSchema.
+type ConfigSelector struct {
+	Name               string   `mapstructure:"-"`
+	ReadExternalConfig bool     `mapstructure:"external_config" description:"Read the values from the external config provider with the name of the selector."`
+	TargetVar          string   `mapstructure:"target_var" description:"The environment variable the selector sets."`
+	PossibleValues     []string `mapstructure:"possible_values" description:"The values to pick from."`
+	ValueTemplate      string   `mapstructure:"value_template" description:"Go text/template rendering the value written for the picked item, e.g. {{ .Env.HOME }}/.kube/{{ .Value }}.yaml."`
+
+	// Dangerous are glob patterns of items that need to be confirmed before they are picked, e.g. prod*.
+	Dangerous []string `mapstructure:"dangerous" description:"Glob patterns of values that need to be confirmed before they are picked, e.g. prod*."`
+
+	// DangerousColor is the colour dangerous items are shown in, the error colour of the theme if empty.
+	DangerousColor string `mapstructure:"dangerous_color" description:"Colour dangerous values are shown in, as hex colour or ANSI colour number. Defaults to the error colour of the theme."`
+
+	// Meta maps possible values to metadata about them, available to the value template and shown in the preview.
+	Meta map[string]map[string]string `mapstructure:"meta" description:"Metadata of the possible values, available to the value template as .Meta and shown in the preview."`
+}
+
+// Read is a method that reads the configuration values from the selector.
+func (s *ConfigSelector) Read() (string, []string, error) {
+	return s.TargetVar, s.PossibleValues, nil
+}
+
+// IntoExternalConfigSelector converts the config selector into a external provider selector
+//
+// For example, the external provider selector for AWS will read profiles set in the AWS config (~/.aws/config)
+func (s *ConfigSelector) IntoExternalConfigSelector(root *Root) (Selector, error) {
+	return GetExternalConfigSelector(root, s.Name)
+}
+
+// configSelectorMap maps selector name to ConfigSelector.
+type ConfigSelectorMap map[string]*ConfigSelector
+
+// Config holds a parsed sevp configuration.
+//
+// A Config is parsed once and is safe to pass around; it does not depend on any global state.
+type Config struct {
+	// Default is the selector used when none is given on the command line.
+	Default string
+
+	// File is the path of the user config file, or empty if the config was not read from a file.
+	File string
+
+	// Files are all config files, in the order they were layered on top of each other.
+	Files []ConfigFile
+
+	// Options are the global options, merged across all config files.
+	Options Options
+
+	// rawOptions are the raw values of the options, see mergeOptions.
+	rawOptions map[string]any
+
+	// Theme is the theme of the TUI and the CLI output, merged across all config files.
+	Theme Theme
+
+	// rawTheme are the raw values of the theme, see mergeTheme.
+	rawTheme map[string]any
+
+	// Keys are the key bindings of the TUI, merged across all config files.
+	Keys Keys
+
+	// rawKeys are the raw values of the keys, see mergeKeys.
+	rawKeys map[string]any
+
+	// sets maps the names of sets to the selectors and items they pick, see ResolveSet.
+	sets map[string]map[string]string
+
+	// setLocations maps the selectors of sets, as "set.selector", to where they were last set.
+	setLocations map[string]location
+
+	// values maps selectors to the items to pick in the directory of the project configs, see DirValues.
+	values map[string]string
+
+	// valueLocations maps the selectors of the values to where they were last set.
+	valueLocations map[string]location
+
+	// defaultSource is the file the default selector was set in.
+	defaultSource ConfigFile
+
+	// defaultLine is the line the default selector was set on in its file.
+	defaultLine int
+
+	// includes are the include patterns of the config file, see loadConfigFile.
+	includes []string
+
+	// diagnostics are the problems found while parsing the config files, see Validate.
+	diagnostics []Diagnostic
+
+	// sections holds every section of the config, valid or not.
+	// Sections are only decoded and validated when they are requested.
+	sections map[string]*section
+}
+
+// ConfigPath returns the path of the user config file to use.
+//
+// The override (e.g. from the --config flag) takes precedence over the SEVP_CONFIG environment variable.
+// Without either, the first existing file of $XDG_CONFIG_HOME/sevp.toml (or $HOME/.config/sevp.toml if
+// XDG_CONFIG_HOME is not set) and $HOME/sevp.toml is used. If neither exists, the former is returned.
+//
+// In each directory, sevp.yaml, sevp.yml and sevp.json are looked for as well, with sevp.toml taking precedence
+// over sevp.yaml and sevp.yml, which take precedence over sevp.json.
+func ConfigPath(root *Root, override string) string {
+	if override != "" {
+		return override
+	}
+
+	if envPath := os.Getenv("SEVP_CONFIG"); envPath != "" {
+		return envPath
+	}
+
+	configDir := root.HomePath(".config")
+	// XDG_CONFIG_HOME must be an absolute path, relative ones are ignored as per the spec
+	if xdgConfigHome := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(xdgConfigHome) {
+		configDir = xdgConfigHome
+	}
+
+	candidates := []string{
+		filepath.Join(configDir, "sevp.toml"),
+		root.HomePath("sevp.toml"),
+	}
+
+	for _, candidate := range candidates {
+		if configPath, exists, _ := findConfigFile(root.Fs, candidate); exists {
+			return configPath
+		}
+	}
+
+	return candidates[0]
+}
+
+// InitConfig reads all layers of the configuration, with the user config file at the given path of the root.
+//
+// The layers are, from lowest to highest precedence: the system config, the team config,
+// the user config and the project configs of the working directory. See Layer for details.
+//
+// If no config file exists at all, a default user config is created at the path
+// and ErrDefaultConfigCreated is returned.
+func InitConfig(root *Root, configPath string) (*Config, error) {
+	// the system config may be in any format, unless its path is set explicitly
+	systemPath := SystemConfigPath()
+	if os.Getenv("SEVP_SYSTEM_CONFIG") == "" {
+		var err error
+		if systemPath, _, err = findConfigFile(root.Fs, systemPath); err != nil {
+			return nil, err
+		}
+	}
+
+	files := []ConfigFile{{Path: systemPath, Layer: LayerSystem}}
+	if teamPath := TeamConfigPath(); teamPath != "" {
+		files = append(files, ConfigFile{Path: teamPath, Layer: LayerTeam})
+	}
+	files = append(files, ConfigFile{Path: configPath, Layer: LayerUser})
+
+	projectPaths, err := FindProjectConfigs(root, root.Workdir)
+	if err != nil {
+		return nil, err
+	}
+	for _, projectPath := range projectPaths {
+		files = append(files, ConfigFile{Path: projectPath, Layer: LayerProject})
+	}
+
+	cfg := &Config{File: configPath, sections: make(map[string]*section)}
+
+	for _, file := range files {
+		layer, err := loadConfigFile(root, file)
+		if err != nil {
+			// only the system config and the user config are optional
+			if errors.Is(err, os.ErrNotExist) && (file.Layer == LayerSystem || file.Layer == LayerUser) {
+				slog.Debug("Config file not found, skipping", "path", file.Path, "layer", file.Layer)
+				continue
+			}
+			return nil, err
+		}
+		cfg.merge(layer)
+	}
+
+	// if no config file is found, create a default one
+	if len(cfg.Files) == 0 {
+		slog.Debug("Config file not found, creating default config")
+		return nil, createDefaultConfig(root, configPath)
+	}
+
+	return cfg, nil
+}
+
+// createDefaultConfig creates a default config file at the given path of the root.
+func createDefaultConfig(root *Root, configPath string) error {
+	// Ensure the directory exists
+	if err := root.Fs.MkdirAll(filepath.Dir(configPath), 0750); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	// The default config is written in TOML, configs in other formats lose its comments
+	content := []byte(defaultConfig)
+	if format := FormatOf(configPath); format != FormatTOML {
+		var err error
+		if content, err = ConvertConfig(content, FormatTOML, format); err != nil {
+			return err
+		}
+	}
+
+	// Write the default config
+	if err := afero.WriteFile(root.Fs, configPath, content, 0600); err != nil {
+		return fmt.Errorf("failed to write default config: %w", err)
+	}
+
+	slog.Debug("Default config created", "path", configPath)
+
+	// Default file creation will still return an error
+	// so the main CLI can exit once and prompt users to run sevp again
+	return ErrDefaultConfigCreated
+}
+
+// LoadConfig reads and parses the config file at the given path of the root as a user config.
+//
+// If the file does not exist, the returned error wraps os.ErrNotExist.
+func LoadConfig(root *Root, configPath string) (*Config, error) {
+	cfg, err := loadConfigFile(root, ConfigFile{Path: configPath, Layer: LayerUser})
+	if err != nil {
+		return nil, err
+	}
+	cfg.File = configPath
+
+	return cfg, nil
+}
+
+// loadConfigFile reads and parses a config file of the root together with all files it includes.
+func loadConfigFile(root *Root, file ConfigFile) (*Config, error) {
+	return newIncludeLoader(root).load(file, nil)
+}
+
+// readConfigFile reads and parses a single config file of the root.
+func readConfigFile(root *Root, file ConfigFile) (*Config, error) {
+	f, err := root.Fs.Open(filepath.Clean(file.Path))
+	if err != nil {
+		slog.Debug("Error reading config", "err", err)
+		return nil, err
+	}
+	defer f.Close()
+
+	var cfg *Config
+	if filepath.Base(file.Path) == DirValuesFileName {
+		cfg, err = readDirValues(f)
+	} else {
+		cfg, err = ReadConfigFormat(f, FormatOf(file.Path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file.Path, err)
+	}
+	cfg.setSource(file)
+
+	slog.Debug("Config file read successfully", "path", file.Path, "layer", file.Layer)
+
+	return cfg, nil
+}
+
+// ReadConfig parses a TOML config from the reader.
+//
+// Problems that don't prevent parsing are not returned as errors but collected for Config.Validate.
+func ReadConfig(r io.Reader) (*Config, error) {
+	return ReadConfigFormat(r, FormatTOML)
+}
+
+// ReadConfigFormat parses a config in the format from the reader, see ReadConfig.
+func ReadConfigFormat(r io.Reader, format Format) (*Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := readSettings(data, format)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := v.AllSettings()
+	if len(settings) == 0 {
+		return nil, errEmptyConfig
+	}
+
+	lines := keyLines(data, format)
+
+	cfg := &Config{
+		Default:     v.GetString("default"),
+		defaultLine: lines["default"],
+		sections:    make(map[string]*section),
+	}
+
+	for key, value := range settings {
+		// selectors of configs written before the key was reserved must not be read as something else
+		if slices.Contains(reservedKeys, key) && isSelectorTable(value) {
+			return nil, fmt.Errorf("%s is reserved and can't be used as a selector name, rename the selector", key)
+		}
+
+		switch key {
+		case "default":
+			continue
+		case "include":
+			for _, pattern := range toSlice(value) {
+				cfg.includes = append(cfg.includes, fmt.Sprint(pattern))
+			}
+			continue
+		case "options":
+			raw, ok := value.(map[string]any)
+			if !ok {
+				return nil, errors.New("options must be a table")
+			}
+			if cfg.Options, err = decodeOptions(raw); err != nil {
+				return nil, err
+			}
+			cfg.rawOptions = raw
+			continue
+		case "theme":
+			raw, ok := value.(map[string]any)
+			if !ok {
+				return nil, errors.New("theme must be a table")
+			}
+			if cfg.Theme, err = decodeTheme(raw); err != nil {
+				return nil, err
+			}
+			cfg.rawTheme = raw
+			continue
+		case "keys":
+			raw, ok := value.(map[string]any)
+			if !ok {
+				return nil, errors.New("keys must be a table")
+			}
+			if cfg.Keys, err = decodeKeys(raw); err != nil {
+				return nil, err
+			}
+			cfg.rawKeys = raw
+			continue
+		case "sets":
+			if cfg.sets, err = readSets(value); err != nil {
+				return nil, err
+			}
+			cfg.setLocations = make(map[string]location)
+			for name, set := range cfg.sets {
+				for selector := range set {
+					key := name + "." + selector
+					cfg.setLocations[key] = location{line: lines["sets."+key]}
+				}
+			}
+			continue
+		case "values":
+			if cfg.values, err = readItems(value); err != nil {
+				return nil, fmt.Errorf("values: %w", err)
+			}
+			cfg.valueLocations = make(map[string]location)
+			for selector := range cfg.values {
+				cfg.valueLocations[selector] = location{line: lines["values."+selector]}
+			}
+			continue
+		}
+
+		raw, ok := value.(map[string]any)
+		if !ok {
+			cfg.diagnostics = append(cfg.diagnostics, Diagnostic{
+				Line:    lines[key],
+				Key:     key,
+				Message: "unknown top-level key, selectors must be tables",
+			})
+			continue
+		}
+
+		s, err := newSection(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %s: %w", key, err)
+		}
+		s.line = lines[key]
+		for field := range s.raw {
+			s.locations[field] = location{line: lines[key+"."+field]}
+		}
+
+		cfg.sections[key] = s
+		cfg.diagnostics = append(cfg.diagnostics, checkSection(key, s, lines)...)
+	}
+
+	return cfg, nil
+}
+
+// isSelectorTable reports whether the value is a table with any of the keys of a selector.
+func isSelectorTable(value any) bool {
+	table, ok := value.(map[string]any)
+	if !ok {
+		return false
+	}
+
+	for _, key := range []string{"target_var", "possible_values", "external_config"} {
+		if _, ok := table[key]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FromConfig returns the named selector of the config.
+//
+// References to environment variables and commands in its values are expanded, see Options.
+// The selector is expanded once, so commands run only on the first call, and later calls return the same selector.
+func (c *Config) FromConfig(name string) (*ConfigSelector, error) {
+	sec, ok := c.sections[name]
+	if !ok {
+		return nil, fmt.Errorf("invalid selector: %s - the selector is not in the config", name)
+	}
+
+	if sec.selector == nil && sec.selectorErr == nil {
+		sec.selector, sec.selectorErr = c.expandSection(name, sec)
+	}
+
+	return sec.selector, sec.selectorErr
+}
+
+// expandSection decodes, expands and checks the section of the named selector.
+func (c *Config) expandSection(name string, sec *section) (*ConfigSelector, error) {
+	s, err := sec.decode(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %s - %w", name, err)
+	}
+
+	if err := c.expander(name, false).expandSelector(s); err != nil {
+		return nil, fmt.Errorf("invalid selector: %s - %w", name, err)
+	}
+
+	if err := checkSelector(s); err != nil {
+		return nil, fmt.Errorf("invalid selector: %s - %w", name, err)
+	}
+
+	// the target variable is written to the state file the shell evaluates, so it must be a plain name
+	if !s.ReadExternalConfig && !envVarPattern.MatchString(s.TargetVar) {
+		return nil, fmt.Errorf("invalid selector: %s - `target_var` %q is not a valid environment variable name", name, s.TargetVar)
+	}
+
+	return s, nil
+}
+
+// expander returns the expander for the values of the named selector of the config.
+func (c *Config) expander(name string, dryRun bool) expander {
+	e := expander{allowCommands: c.Options.AllowCommands, dryRun: dryRun}
+	if sec, ok := c.sections[name]; ok {
+		e.projectValues = sec.projectValues
+	}
+
+	return e
+}
+
+// GetSelector returns the appropriate selector based on CLI args and config.
+//
+// External config providers read from the given root.
+func (c *Config) GetSelector(root *Root, args []string) (Selector, error) {
+	// Check for default selector for when no args are provided
+	selectorName := c.Default
+
+	// If target selector is provided, use it
+	if len(args) == 1 {
+		selectorName = args[0]
+	}
+
+	section, err := c.FromConfig(selectorName)
+	if err != nil {
+		return nil, err
+	}
+
+	// If the selector is an external config provider,
+	// converts the config selector into a external config selector
+	if section.ReadExternalConfig {
+		selector, err := section.IntoExternalConfigSelector(root)
+		if err != nil || (section.ValueTemplate == "" && len(section.Meta) == 0) {
+			return selector, err
+		}
+		return &templatedSelector{Selector: selector, config: section}, nil
+	}
+
+	if section.TargetVar == "" || len(section.PossibleValues) == 0 {
+		return nil, errors.New("missing target_var or possible_values")
+	}
+
+	return section, nil
+}
+
+// ParseSelectors returns a map of all selectors defined in the config.
+//
+// It fails if any of the selectors is invalid.
+func (c *Config) ParseSelectors() (ConfigSelectorMap, error) {
+	selectors := make(ConfigSelectorMap)
+
+	for key := range c.sections {
+		s, err := c.FromConfig(key)
+		if err != nil {
+			return nil, fmt.Errorf("error processing selector %s: %v", key, err)
+		}
+		selectors[key] = s
+	}
+
+	return selectors, nil
+}
+
+// Names returns the sorted names of all selectors defined in the config, valid or not.
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.sections))
+	for name := range c.sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// decodeSelector decodes the raw values of a section into a ConfigSelector.
+func decodeSelector(name string, raw map[string]any) (*ConfigSelector, error) {
+	s := &ConfigSelector{Name: name}
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		// automatic type casting, e.g. from string to []string, just like viper does
+		WeaklyTypedInput: true,
+		Result:           s,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := decoder.Decode(raw); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
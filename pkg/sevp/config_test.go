@@ -1,11 +1,11 @@
-package internal
+package sevp
 
 import (
 	"os"
 	"strings"
 	"testing"
 
-	"github.com/spf13/viper"
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -22,10 +22,7 @@ external_config = false
 target_var = "CUSTOM_VAR"
 possible_values = ["value1", "value2"]
 `
-	viper.Reset()
-	viper.SetConfigType("toml")
-	viper.SetConfigFile("test.toml")
-	err := viper.ReadConfig(strings.NewReader(configContent))
+	cfg, err := ReadConfig(strings.NewReader(configContent))
 	assert.NoError(t, err, "expected no error reading config")
 
 	tests := []struct {
@@ -57,7 +54,7 @@ possible_values = ["value1", "value2"]
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			result, err := FromConfig(test.selector)
+			result, err := cfg.FromConfig(test.selector)
 			assert.NoError(t, err, "expected no error creating selector")
 			assert.Equal(t, test.expected, result, "selector should match expected")
 		})
@@ -79,13 +76,10 @@ external_config = false
 target_var = "CUSTOM_VAR"
 possible_values = ["value1", "value2"]
 `
-	viper.Reset()
-	viper.SetConfigType("toml")
-	viper.SetConfigFile("test.toml")
-	err := viper.ReadConfig(strings.NewReader(configContent))
+	cfg, err := ReadConfig(strings.NewReader(configContent))
 	assert.NoError(t, err, "expected no error reading config")
 
-	selectors, err := ParseSelectorsFromConfig()
+	selectors, err := cfg.ParseSelectors()
 	assert.NoError(t, err, "expected no error getting selectors")
 
 	expectedSelectors := ConfigSelectorMap{
@@ -116,16 +110,12 @@ external_config = true
 target_var = "AWS_PROFILE"
 possible_values = ["default", "profile1", "profile2"]
 `
-	viper.Reset()
-	viper.SetConfigType("toml")
-	viper.SetConfigFile("test.toml")
-
-	err := viper.ReadConfig(strings.NewReader(configContent))
+	cfg, err := ReadConfig(strings.NewReader(configContent))
 	assert.NoError(t, err, "expected no error reading config")
 
-	selectorChoice := viper.GetString("default")
+	selectorChoice := cfg.Default
 
-	selectors, err := ParseSelectorsFromConfig()
+	selectors, err := cfg.ParseSelectors()
 	assert.NoError(t, err, "expected no error getting selectors")
 
 	selector, ok := selectors[selectorChoice]
@@ -172,19 +162,29 @@ func TestInvalidConfiguration(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			viper.Reset()
-			viper.SetConfigType("toml")
-			viper.SetConfigFile("test.toml")
-			err := viper.ReadConfig(strings.NewReader(test.configContent))
+			cfg, err := ReadConfig(strings.NewReader(test.configContent))
 			assert.NoError(t, err, "expected no error reading config")
 
-			_, err = FromConfig("invalid")
+			_, err = cfg.FromConfig("invalid")
 			assert.Error(t, err, "expected error for invalid config")
 			assert.Contains(t, err.Error(), test.expectedErr, "error should mention the invalid field")
 		})
 	}
 }
 
+// Selectors named like reserved keys should be reported instead of being read as something else
+func TestReservedSelectorNames(t *testing.T) {
+	for _, key := range reservedKeys {
+		t.Run(key, func(t *testing.T) {
+			_, err := ReadConfig(strings.NewReader("[" + key + "]\ntarget_var = \"X\"\npossible_values = [\"a\"]\n"))
+			assert.ErrorContains(t, err, key+" is reserved and can't be used as a selector name")
+		})
+	}
+
+	_, err := ReadConfig(strings.NewReader("[keys]\nexternal_config = true\n"))
+	assert.ErrorContains(t, err, "keys is reserved")
+}
+
 // Automatic inference and type casting should work as expected
 func TestAutomaticInference(t *testing.T) {
 	configContent := `
@@ -192,13 +192,10 @@ func TestAutomaticInference(t *testing.T) {
 target_var = "AWS_PROFILE"
 possible_values = "val"
 `
-	viper.Reset()
-	viper.SetConfigType("toml")
-	viper.SetConfigFile("test.toml")
-	err := viper.ReadConfig(strings.NewReader(configContent))
+	cfg, err := ReadConfig(strings.NewReader(configContent))
 	assert.NoError(t, err, "expected no error reading config")
 
-	s, err := FromConfig("test")
+	s, err := cfg.FromConfig("test")
 	assert.NoError(t, err, "expected no error for valid config")
 
 	// automatic type castig from string to []string by viper
@@ -226,13 +223,10 @@ external_config = false
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			viper.Reset()
-			viper.SetConfigType("toml")
-			viper.SetConfigFile("test.toml")
-			err := viper.ReadConfig(strings.NewReader(test.configContent))
+			cfg, err := ReadConfig(strings.NewReader(test.configContent))
 			assert.NoError(t, err, "expected no error reading config")
 
-			_, err = ParseSelectorsFromConfig()
+			_, err = cfg.ParseSelectors()
 			assert.Error(t, err, "expected error for empty config")
 		})
 	}
@@ -258,10 +252,7 @@ target_var = "SPECIAL_VAR!@#$"
 possible_values = ["value-with-dash", "value_with_underscore", "value with spaces", "!@#$%^&*()"]
 `
 
-	viper.Reset()
-	viper.SetConfigType("toml")
-	viper.SetConfigFile("test.toml")
-	err := viper.ReadConfig(strings.NewReader(configContent))
+	cfg, err := ReadConfig(strings.NewReader(configContent))
 	assert.NoError(t, err, "expected no error reading config")
 
 	tests := []struct {
@@ -297,7 +288,7 @@ possible_values = ["value-with-dash", "value_with_underscore", "value with space
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			selector, err := FromConfig(test.selector)
+			selector, err := cfg.FromConfig(test.selector)
 			assert.NoError(t, err, "expected no error creating selector")
 			test.check(t, selector)
 		})
@@ -317,9 +308,7 @@ external_config = false
 target_var = "SECOND_VAR"
 possible_values = ["second"]
 `
-	viper.Reset()
-	viper.SetConfigType("toml")
-	err := viper.ReadConfig(strings.NewReader(configContent))
+	_, err := ReadConfig(strings.NewReader(configContent))
 	assert.Error(t, err, "expected error reading config with duplicate selectors")
 	assert.Contains(t, err.Error(), "duplicate", "error should mention the duplicate table")
 }
@@ -332,85 +321,184 @@ external_config = true
 target_var = "TEST_VAR"
 possible_values = ["value"]
 `
-	viper.Reset()
-	viper.SetConfigType("toml")
-	err := viper.ReadConfig(strings.NewReader(configContent))
+	cfg, err := ReadConfig(strings.NewReader(configContent))
 	assert.NoError(t, err, "expected no error reading config")
 
-	_, err = FromConfig("non_existent")
+	_, err = cfg.FromConfig("non_existent")
 	assert.Error(t, err, "expected error for non-existent selector")
 	assert.Contains(t, err.Error(), "the selector is not in the config", "error should mention the missing selector")
 }
 
-// When no config file is found, the error message should mention the missing config file
+// When no config file is found, loading the config should fail with a not-exist error
 func TestNoConfigFile(t *testing.T) {
-	_, err := FromConfig("no config")
-	assert.Error(t, err, "expected error")
-	assert.Contains(t, err.Error(), "the config file is not found", "error should mention the missing config file")
-
-	_, err = ParseSelectorsFromConfig()
+	_, err := LoadConfig(newTestRoot(), "/home/test/.config/sevp.toml")
 	assert.Error(t, err, "expected error")
-	assert.Contains(t, err.Error(), "no config file found", "error should mention the missing config file")
+	assert.ErrorIs(t, err, os.ErrNotExist, "error should mention the missing config file")
 }
 
-// Initializing the configuration should fail or succeed based on the presence of the config file
+// Initializing the configuration should create a default config if none is present
 func TestInitConfig(t *testing.T) {
-	// backup and restore environment variables
-	originalHome := os.Getenv("HOME")
-	defer os.Setenv("HOME", originalHome)
-
-	// set up a temporary home directory
-	tempHome := t.TempDir()
-	os.Setenv("HOME", tempHome)
-
-	// test case: no config file and no aws config
-	err := InitConfig()
-	assert.Error(t, err, "Expected error when no config files are present")
-
-	// test case: valid aws config file
-	awsConfigPath := tempHome + "/.aws/config"
-	err = os.MkdirAll(tempHome+"/.aws", 0755)
-	assert.NoError(t, err, "Failed to create .aws directory")
-	err = os.WriteFile(awsConfigPath, []byte("[default]\nregion=us-east-1"), 0644)
-	assert.NoError(t, err, "Failed to create aws config file")
-
-	err = InitConfig()
-	assert.NoError(t, err, "Expected no error when AWS config file is present")
+	root := newTestRoot()
+	configPath := "/home/test/.config/sevp.toml"
+
+	// test case: no config file
+	_, err := InitConfig(root, configPath)
+	assert.ErrorIs(t, err, ErrDefaultConfigCreated, "Expected error when no config files are present")
+	exists, err := afero.Exists(root.Fs, configPath)
+	assert.NoError(t, err)
+	assert.True(t, exists, "Expected the default config to be created")
+
+	// test case: default config file was created
+	cfg, err := InitConfig(root, configPath)
+	assert.NoError(t, err, "Expected no error when the config file is present")
+	assert.Equal(t, "aws", cfg.Default, "Expected the default selector of the default config")
+	assert.Equal(t, configPath, cfg.File, "Expected the config file path to be set")
+}
+
+// The config path should be taken from the override, SEVP_CONFIG or the home directory in that order
+func TestConfigPath(t *testing.T) {
+	root := newTestRoot()
+	t.Setenv("SEVP_CONFIG", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	assert.Equal(t, "/home/test/.config/sevp.toml", ConfigPath(root, ""))
+
+	// $HOME/sevp.toml is used if it is the only existing config
+	assert.NoError(t, afero.WriteFile(root.Fs, "/home/test/sevp.toml", []byte(""), 0600))
+	assert.Equal(t, "/home/test/sevp.toml", ConfigPath(root, ""))
+
+	// XDG_CONFIG_HOME is honoured and takes precedence over $HOME/sevp.toml once it exists
+	t.Setenv("XDG_CONFIG_HOME", "/home/test/xdg")
+	assert.Equal(t, "/home/test/sevp.toml", ConfigPath(root, ""))
+	assert.NoError(t, afero.WriteFile(root.Fs, "/home/test/xdg/sevp.toml", []byte(""), 0600))
+	assert.Equal(t, "/home/test/xdg/sevp.toml", ConfigPath(root, ""))
+
+	// relative XDG_CONFIG_HOME is ignored
+	t.Setenv("XDG_CONFIG_HOME", "xdg")
+	assert.Equal(t, "/home/test/sevp.toml", ConfigPath(root, ""))
+
+	t.Setenv("SEVP_CONFIG", "/tmp/env.toml")
+	assert.Equal(t, "/tmp/env.toml", ConfigPath(root, ""))
+
+	assert.Equal(t, "/tmp/flag.toml", ConfigPath(root, "/tmp/flag.toml"))
 }
 
 // Getting the selector should return a valid selector or an error based on the configuration
 func TestGetSelector(t *testing.T) {
-	// backup and restore viper configuration
-	originalConfig := viper.AllSettings()
-	defer func() {
-		viper.Reset()
-		for key, value := range originalConfig {
-			viper.Set(key, value)
-		}
-	}()
-
-	// test case: no config file used
-	selector, err := GetSelector([]string{})
-	assert.NoError(t, err, "Expected no error when no config file is used")
+	configContent := `
+default = "aws"
+
+[aws]
+external_config = true
+target_var = "AWS_PROFILE"
+
+[invalid_selector]
+external_config = false
+target_var = ""
+possible_values = []
+
+[valid_selector]
+external_config = false
+target_var = "TEST_VAR"
+possible_values = ["value1", "value2"]
+`
+	cfg, err := ReadConfig(strings.NewReader(configContent))
+	assert.NoError(t, err, "expected no error reading config")
+
+	// test case: default selector
+	selector, err := cfg.GetSelector(newTestRoot(), []string{})
+	assert.NoError(t, err, "Expected no error for the default selector")
 	assert.NotNil(t, selector, "Expected a valid selector")
 
 	// test case: invalid selector in config
-	viper.SetConfigFile("non-existent.toml")
-	viper.Set("invalid_selector.external_config", false)
-	viper.Set("invalid_selector.target_var", "")
-	viper.Set("invalid_selector.possible_values", []string{})
-
-	s, err := GetSelector([]string{"invalid_selector"})
-	t.Log("Selector:", s)
-	t.Log("Error:", err)
+	_, err = cfg.GetSelector(newTestRoot(), []string{"invalid_selector"})
 	assert.Error(t, err, "Expected error for invalid selector configuration")
 
 	// test case: valid selector in config
-	viper.Set("valid_selector.external_config", false)
-	viper.Set("valid_selector.target_var", "TEST_VAR")
-	viper.Set("valid_selector.possible_values", []string{"value1", "value2"})
-
-	selector, err = GetSelector([]string{"valid_selector"})
+	selector, err = cfg.GetSelector(newTestRoot(), []string{"valid_selector"})
 	assert.NoError(t, err, "Expected no error for valid selector configuration")
 	assert.NotNil(t, selector, "Expected a valid selector")
 }
+
+// Configs should be independent of each other
+func TestConfigsAreIndependent(t *testing.T) {
+	t.Parallel()
+
+	first, err := ReadConfig(strings.NewReader(`
+[shared]
+target_var = "FIRST_VAR"
+possible_values = ["first"]
+`))
+	assert.NoError(t, err, "expected no error reading config")
+
+	second, err := ReadConfig(strings.NewReader(`
+[shared]
+target_var = "SECOND_VAR"
+possible_values = ["second"]
+`))
+	assert.NoError(t, err, "expected no error reading config")
+
+	firstSelector, err := first.FromConfig("shared")
+	assert.NoError(t, err)
+	secondSelector, err := second.FromConfig("shared")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "FIRST_VAR", firstSelector.TargetVar)
+	assert.Equal(t, "SECOND_VAR", secondSelector.TargetVar)
+}
+
+// Project configs should be layered on top of the user config
+func TestInitConfigWithProjectConfigs(t *testing.T) {
+	root := newTestRoot()
+	root.Workdir = "/home/test/repo/service"
+
+	files := map[string]string{
+		"/home/test/.config/sevp.toml": `
+default = "aws"
+
+[aws]
+target_var = "AWS_PROFILE"
+possible_values = ["personal"]
+
+[some_var]
+target_var = "SOME_VAR"
+possible_values = ["global"]
+`,
+		"/home/test/repo/.git/HEAD": "",
+		"/home/test/repo/.sevp.toml": `
+default = "kube"
+
+[aws]
+possible_values = ["acme-dev", "acme-prod"]
+
+[kube]
+target_var = "KUBECONFIG"
+possible_values = ["dev.yaml"]
+`,
+		"/home/test/repo/service/.sevp.toml": `
+[kube]
+target_var = "KUBECONFIG"
+possible_values = ["service.yaml"]
+`,
+	}
+	for filePath, content := range files {
+		assert.NoError(t, afero.WriteFile(root.Fs, filePath, []byte(content), 0600))
+	}
+
+	cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	assert.NoError(t, err, "expected no error initializing config")
+
+	assert.Equal(t, "kube", cfg.Default, "project default should replace the user default")
+	assert.Equal(t, "/home/test/.config/sevp.toml", cfg.File)
+	assert.Equal(t, []ConfigFile{
+		{Path: "/home/test/.config/sevp.toml", Layer: LayerUser},
+		{Path: "/home/test/repo/.sevp.toml", Layer: LayerProject},
+		{Path: "/home/test/repo/service/.sevp.toml", Layer: LayerProject},
+	}, cfg.Files, "files should be layered from the user config to the innermost project config")
+
+	selectors, err := cfg.ParseSelectors()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"personal", "acme-dev", "acme-prod"}, selectors["aws"].PossibleValues, "project values should be added to the user values")
+	assert.Equal(t, []string{"global"}, selectors["some_var"].PossibleValues)
+	assert.Equal(t, []string{"service.yaml"}, selectors["kube"].PossibleValues, "project selectors should be layered like others")
+}
@@ -0,0 +1,113 @@
+package sevp
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Resolve returns the assignment of the item picked from the named selector.
+//
+// The selector is read, so the item is checked against its possible values.
+func (c *Config) Resolve(root *Root, selector string, item string) (Assignment, error) {
+	assignments, err := c.resolveItems(root, map[string]string{strings.ToLower(selector): item})
+	if err != nil {
+		return Assignment{}, err
+	}
+
+	return assignments[0], nil
+}
+
+// IsDangerous reports whether the item of the named selector matches one of its dangerous patterns.
+//
+// Unknown and invalid selectors have no dangerous items.
+func (c *Config) IsDangerous(selector string, item string) bool {
+	s, err := c.FromConfig(selector)
+	if err != nil {
+		return false
+	}
+
+	return s.IsDangerous(item)
+}
+
+// IsDangerousValue reports whether the value is written for a dangerous item of the named selector,
+// e.g. to check values restored from the history, which records values rather than items.
+//
+// Unknown and invalid selectors have no dangerous values.
+func (c *Config) IsDangerousValue(selector string, value string) bool {
+	s, err := c.FromConfig(selector)
+	if err != nil || len(s.Dangerous) == 0 {
+		return false
+	}
+
+	for _, item := range s.PossibleValues {
+		if !s.IsDangerous(item) {
+			continue
+		}
+		if rendered, err := RenderValue(s, item); err == nil && rendered == value {
+			return true
+		}
+	}
+
+	return s.IsDangerous(value)
+}
+
+// Restores returns the assignments restoring the old values of the changes, e.g. to confirm dangerous ones
+// before Undo or Back reverts them, see RevertCheck. Changes that unset their variable restore nothing.
+//
+// The dangerous patterns are matched against the recorded items as well as the values, so values rendered
+// from templates and items of external configs are recognised too.
+func (c *Config) Restores(changes []HistoryEntry) []Assignment {
+	var restores []Assignment
+	for _, change := range changes {
+		if change.Old == nil {
+			continue
+		}
+
+		selector := change.oldSelector()
+		item := change.OldItem
+		if item == "" {
+			item = *change.Old
+		}
+
+		restores = append(restores, Assignment{
+			Selector:  selector,
+			TargetVar: change.Var,
+			Item:      item,
+			Value:     *change.Old,
+			Dangerous: c.IsDangerous(selector, item) || c.IsDangerousValue(selector, *change.Old),
+		})
+	}
+
+	return restores
+}
+
+// IsDangerous reports whether the item matches one of the dangerous patterns of the selector, see path.Match.
+// Invalid patterns never match, they are reported by Config.Validate.
+func (s *ConfigSelector) IsDangerous(item string) bool {
+	for _, pattern := range s.Dangerous {
+		if ok, _ := path.Match(pattern, item); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkDangerous checks the dangerous patterns and colour of the selector.
+func (c *Config) checkDangerous(name string, s *ConfigSelector) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	for _, pattern := range s.Dangerous {
+		if _, err := path.Match(pattern, ""); err != nil {
+			diagnostics = append(diagnostics, c.keyDiagnostic(name, "dangerous", fmt.Sprintf("invalid pattern %q: %v", pattern, err)))
+		}
+	}
+
+	if s.DangerousColor != "" && !colourPattern.MatchString(s.DangerousColor) {
+		diagnostics = append(diagnostics, c.keyDiagnostic(name, "dangerous_color", fmt.Sprintf(
+			"invalid colour %q, expected a hex colour like #B198E5 or an ANSI colour number", s.DangerousColor)))
+	}
+
+	return diagnostics
+}
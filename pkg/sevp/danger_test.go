@@ -0,0 +1,202 @@
+package sevp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Items matching the dangerous patterns of their selector should be dangerous
+func TestIsDangerous(t *testing.T) {
+	cfg, err := ReadConfig(strings.NewReader(`
+[aws]
+target_var = "AWS_PROFILE"
+possible_values = ["dev", "prod", "prod-eu", "acme-production"]
+dangerous = ["prod*", "*-production"]
+dangerous_color = "#FF0000"
+
+[kube]
+target_var = "KUBECONFIG"
+possible_values = ["prod"]
+
+[sets.release]
+aws = "prod-eu"
+kube = "prod"
+`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		selector string
+		item     string
+		want     bool
+	}{
+		{"aws", "dev", false},
+		{"aws", "prod", true},
+		{"aws", "prod-eu", true},
+		{"aws", "acme-production", true},
+		{"kube", "prod", false},
+		{"missing", "prod", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector+"/"+tt.item, func(t *testing.T) {
+			assert.Equal(t, tt.want, cfg.IsDangerous(tt.selector, tt.item))
+		})
+	}
+
+	assignments, err := cfg.ResolveSet(newTestRoot(), "release")
+	require.NoError(t, err)
+	assert.Equal(t, []Assignment{
+		{Selector: "aws", TargetVar: "AWS_PROFILE", Item: "prod-eu", Value: "prod-eu", Dangerous: true},
+		{Selector: "kube", TargetVar: "KUBECONFIG", Item: "prod", Value: "prod"},
+	}, assignments)
+
+	assert.Empty(t, cfg.Validate())
+}
+
+// Resolving an item should check it against the possible values of the selector
+func TestResolve(t *testing.T) {
+	cfg, err := ReadConfig(strings.NewReader(`
+[kube]
+target_var = "KUBECONFIG"
+possible_values = ["dev", "prod"]
+value_template = "/kube/{{ .Value }}.yaml"
+dangerous = ["prod"]
+`))
+	require.NoError(t, err)
+
+	assignment, err := cfg.Resolve(newTestRoot(), "KUBE", "prod")
+	require.NoError(t, err)
+	assert.Equal(t, Assignment{Selector: "kube", TargetVar: "KUBECONFIG", Item: "prod", Value: "/kube/prod.yaml", Dangerous: true}, assignment)
+
+	_, err = cfg.Resolve(newTestRoot(), "kube", "staging")
+	assert.ErrorContains(t, err, `"staging" is not a possible value of selector kube`)
+
+	_, err = cfg.Resolve(newTestRoot(), "missing", "dev")
+	assert.ErrorContains(t, err, "invalid selector: missing")
+}
+
+// Invalid dangerous patterns and colours should be reported
+func TestValidateDangerous(t *testing.T) {
+	cfg, err := ReadConfig(strings.NewReader(`
+[aws]
+target_var = "AWS_PROFILE"
+possible_values = ["prod"]
+dangerous = ["prod[", "prod"]
+dangerous_color = "red"
+`))
+	require.NoError(t, err)
+
+	assert.True(t, cfg.IsDangerous("aws", "prod"), "valid patterns still match")
+
+	diagnostics := cfg.Validate()
+	require.Len(t, diagnostics, 2)
+	assert.Equal(t, "aws.dangerous", diagnostics[0].Key)
+	assert.Contains(t, diagnostics[0].Message, `invalid pattern "prod["`)
+	assert.Equal(t, "aws.dangerous_color", diagnostics[1].Key)
+	assert.Contains(t, diagnostics[1].Message, `invalid colour "red"`)
+}
+
+// Values should be dangerous if they are written for a dangerous item
+func TestIsDangerousValue(t *testing.T) {
+	cfg, err := ReadConfig(strings.NewReader(`
+[kube]
+target_var = "KUBECONFIG"
+possible_values = ["dev", "prod"]
+value_template = "/kube/{{ .Value }}.yaml"
+dangerous = ["prod"]
+`))
+	require.NoError(t, err)
+
+	assert.True(t, cfg.IsDangerousValue("kube", "/kube/prod.yaml"))
+	assert.True(t, cfg.IsDangerousValue("kube", "prod"))
+	assert.False(t, cfg.IsDangerousValue("kube", "/kube/dev.yaml"))
+	assert.False(t, cfg.IsDangerousValue("missing", "prod"))
+}
+
+// Restoring dangerous items should be refused, also for external selectors and templated values
+func TestRestores(t *testing.T) {
+	useTestClock(t)
+	root := newTestRoot()
+
+	cfg, err := ReadConfig(strings.NewReader(`
+[aws]
+external_config = true
+value_template = "arn:{{ .Value }}:role"
+dangerous = ["*-prod"]
+`))
+	require.NoError(t, err)
+
+	for _, item := range []string{"acme-prod", "acme-dev"} {
+		require.NoError(t, WriteAssignments(root, SourcePicker, []Assignment{{Selector: "aws", TargetVar: "AWS_PROFILE", Item: item, Value: "arn:" + item + ":role"}}))
+	}
+
+	var restores []Assignment
+	check := func(changes []HistoryEntry) error {
+		restores = cfg.Restores(changes)
+		for _, restore := range restores {
+			if restore.Dangerous {
+				return errors.New("dangerous")
+			}
+		}
+		return nil
+	}
+
+	_, err = Undo(root, check)
+	assert.ErrorContains(t, err, "dangerous")
+	assert.Equal(t, []Assignment{
+		{Selector: "aws", TargetVar: "AWS_PROFILE", Item: "acme-prod", Value: "arn:acme-prod:role", Dangerous: true},
+	}, restores, "the recorded item is matched")
+
+	_, err = Back(root, "aws", check)
+	assert.ErrorContains(t, err, "dangerous")
+
+	state, err := LoadState(root)
+	require.NoError(t, err)
+	entry, ok := state.Entry("AWS_PROFILE")
+	require.True(t, ok)
+	assert.Equal(t, "acme-dev", entry.Item, "refused changes are not reverted")
+
+	// history of earlier versions records values only
+	prod := "acme-prod"
+	assert.True(t, cfg.Restores([]HistoryEntry{{Var: "AWS_PROFILE", Selector: "aws", Old: &prod}})[0].Dangerous)
+	assert.Empty(t, cfg.Restores([]HistoryEntry{{Var: "AWS_PROFILE", Selector: "aws"}}), "unset variables restore nothing")
+}
+
+// Project configs should only add dangerous patterns to selectors of other layers
+func TestDangerousInProjectConfigs(t *testing.T) {
+	root := newTestRoot()
+	root.Workdir = "/home/test/repo"
+	t.Setenv("SEVP_SYSTEM_CONFIG", "")
+	t.Setenv("SEVP_TEAM_CONFIG", "")
+
+	writeTestFiles(t, root, map[string]string{
+		"/home/test/.config/sevp.toml": `
+[aws]
+target_var = "AWS_PROFILE"
+possible_values = ["dev", "staging", "prod"]
+dangerous = ["prod"]
+`,
+		"/home/test/repo/.git/HEAD": "",
+		"/home/test/repo/.sevp.toml": `
+[aws]
+dangerous = ["staging"]
+
+[kube]
+target_var = "KUBECONFIG"
+possible_values = ["dev", "prod"]
+dangerous = ["prod"]
+`,
+	})
+
+	cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	require.NoError(t, err)
+
+	assert.True(t, cfg.IsDangerous("aws", "prod"), "patterns of the user config are kept")
+	assert.True(t, cfg.IsDangerous("aws", "staging"), "patterns of the project config are added")
+	assert.False(t, cfg.IsDangerous("aws", "dev"))
+	assert.True(t, cfg.IsDangerous("kube", "prod"), "selectors of project configs have their own patterns")
+}
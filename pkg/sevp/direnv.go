@@ -0,0 +1,27 @@
+package sevp
+
+import (
+	"fmt"
+)
+
+// DirenvStdlib returns the use_sevp function for direnv, which exports the picked values
+// with "use sevp" in an .envrc and reloads the environment whenever a value is picked,
+// i.e. whenever the state file of the root changes.
+func DirenvStdlib(root *Root) string {
+	return fmt.Sprintf(`use_sevp() {
+    watch_file %s
+    eval "$(sevp direnv)"
+}`, shellQuote(root.OSPath(StateFile(root))))
+}
+
+// Envrc returns the environment variables of the state file of the root as .envrc code for direnv.
+//
+// The values are shell-quoted, just like in the state file the shell hook evaluates.
+func Envrc(root *Root) (string, error) {
+	state, err := LoadState(root)
+	if err != nil {
+		return "", err
+	}
+
+	return state.Script("bash")
+}
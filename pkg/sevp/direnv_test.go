@@ -0,0 +1,71 @@
+package sevp
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The picked values should be exported in the .envrc format, sorted by variable
+func TestEnvrc(t *testing.T) {
+	root := newTestRoot()
+
+	envrc, err := Envrc(root)
+	require.NoError(t, err)
+	assert.Empty(t, envrc, "nothing is exported without a state file")
+
+	require.NoError(t, WriteValues(root, map[string]string{"KUBECONFIG": "/kube/dev.yaml", "AWS_PROFILE": "acme"}))
+
+	envrc, err = Envrc(root)
+	require.NoError(t, err)
+	assert.Equal(t, "export AWS_PROFILE='acme'\nexport KUBECONFIG='/kube/dev.yaml'\n", envrc)
+}
+
+// The use_sevp function should watch the state file of the root
+func TestDirenvStdlib(t *testing.T) {
+	root := newTestRoot()
+	assert.Contains(t, DirenvStdlib(root), "watch_file '/home/test/.sevp'\n")
+
+	root.StatePath = "/home/test/repo/.envrc.local"
+	assert.Contains(t, DirenvStdlib(root), "watch_file '/home/test/repo/.envrc.local'\n")
+}
+
+// The state file option should be relative to the config setting it and stay inside of projects
+func TestStateFileOption(t *testing.T) {
+	t.Setenv("SEVP_SYSTEM_CONFIG", "")
+	t.Setenv("SEVP_TEAM_CONFIG", "")
+
+	tests := []struct {
+		name    string
+		project string
+		want    string
+	}{
+		{name: "relative", project: `state_file = ".envrc.local"`, want: "/home/test/repo/.envrc.local"},
+		{name: "outside of the project", project: `state_file = "../.bashrc"`, want: "/home/test/.state"},
+		{name: "absolute", project: `state_file = "/etc/profile"`, want: "/home/test/.state"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := newTestRoot()
+			root.Workdir = "/home/test/repo"
+			writeTestFiles(t, root, map[string]string{
+				"/home/test/.config/sevp.toml": "[options]\nstate_file = \"../.state\"\n\n[kube]\ntarget_var = \"KUBECONFIG\"\npossible_values = [\"dev\"]\n",
+				"/home/test/repo/.git/HEAD":    "",
+				"/home/test/repo/.sevp.toml":   "[options]\n" + tt.project + "\n",
+			})
+
+			cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, cfg.Options.StateFile)
+
+			root.StatePath = cfg.Options.StateFile
+			require.NoError(t, WriteToFile(root, "dev", "KUBECONFIG"))
+			content, err := afero.ReadFile(root.Fs, tt.want)
+			require.NoError(t, err)
+			assert.Equal(t, "export KUBECONFIG='dev'\n", string(content))
+		})
+	}
+}
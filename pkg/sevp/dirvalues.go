@@ -0,0 +1,346 @@
+package sevp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// Directory values are the items picked automatically when entering the directory of a project,
+// and restored when leaving it. They are declared in the values table of a project config:
+//
+//	[values]
+//	aws = "acme-dev"
+//
+// or in a .sevp-values file next to it, which holds the values table only:
+//
+//	aws = "acme-dev"
+//
+// Just like project configs, values of inner directories take precedence over the ones of outer directories.
+
+const (
+	// DirValuesFileName is the name of files declaring the directory values of a project.
+	DirValuesFileName = ".sevp-values"
+
+	// DirStateFileName is the name of the file in the user's home directory that records
+	// the applied directory values, the values they replaced and the state file they were written to.
+	DirStateFileName = ".sevp-dir"
+)
+
+// readDirValues parses a directory values file from the reader.
+//
+// Empty files are valid, as they may be used to mark a directory without values.
+func readDirValues(r io.Reader) (*Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := readSettings(data, FormatTOML)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := readItems(v.AllSettings())
+	if err != nil {
+		return nil, err
+	}
+
+	lines := tomlKeyLines(data)
+	cfg := &Config{
+		values:         values,
+		valueLocations: make(map[string]location),
+		sections:       make(map[string]*section),
+	}
+	for selector := range values {
+		cfg.valueLocations[selector] = location{line: lines[selector]}
+	}
+
+	return cfg, nil
+}
+
+// mergeValues merges the directory values of another config into the values of the config.
+//
+// Selectors already set are only replaced if override is set.
+func (c *Config) mergeValues(other *Config, override bool) {
+	for selector, item := range other.values {
+		if c.values == nil {
+			c.values = make(map[string]string)
+			c.valueLocations = make(map[string]location)
+		}
+
+		if _, ok := c.values[selector]; ok && !override {
+			continue
+		}
+		c.values[selector] = item
+		c.valueLocations[selector] = other.valueLocations[selector]
+	}
+}
+
+// DirValues returns the directory values of the project configs, mapping selectors to the items to pick from them.
+func (c *Config) DirValues() map[string]string {
+	values := make(map[string]string, len(c.values))
+	for selector, item := range c.values {
+		values[selector] = item
+	}
+
+	return values
+}
+
+// ResolveDirValues returns the assignments of the directory values, ordered by selector.
+//
+// Just like sets, the directory values resolve either completely or not at all, see ResolveSet.
+// Values may only pick selectors defined outside of project configs, so a repository can't define
+// the variables it sets on its own.
+func (c *Config) ResolveDirValues(root *Root) ([]Assignment, error) {
+	for _, selector := range sortedKeys(c.values) {
+		if c.definedInProject(selector) {
+			return nil, fmt.Errorf("directory values: selector %s is defined in a project config, values can only pick selectors of the system, team or user config", selector)
+		}
+	}
+
+	assignments, err := c.resolveItems(root, c.values)
+	if err != nil {
+		return nil, fmt.Errorf("directory values: %w", err)
+	}
+
+	return assignments, nil
+}
+
+// checkValues checks that the directory values reference existing selectors defined outside of project configs
+// and, where known, their possible values.
+func (c *Config) checkValues() []Diagnostic {
+	diagnostics := c.checkItems(c.values, c.valueDiagnostic)
+
+	for _, selector := range sortedKeys(c.values) {
+		if c.definedInProject(selector) {
+			d := c.valueDiagnostic(selector)
+			d.Message = fmt.Sprintf("selector %q is defined in a project config, values can only pick selectors of the system, team or user config", selector)
+			diagnostics = append(diagnostics, d)
+		}
+	}
+
+	return diagnostics
+}
+
+// definedInProject reports whether the selector was first defined in a project config.
+func (c *Config) definedInProject(selector string) bool {
+	s, ok := c.sections[selector]
+	return ok && s.inProject()
+}
+
+// valueDiagnostic creates a diagnostic located where the directory value of the selector was last set.
+func (c *Config) valueDiagnostic(selector string) Diagnostic {
+	loc := c.valueLocations[selector]
+
+	return Diagnostic{File: loc.file, Line: loc.line, Key: "values." + selector}
+}
+
+// dirState records the directory values applied to the state file, see SwitchDirValues.
+type dirState struct {
+	// Applied maps the variables written for the directory to their values.
+	Applied map[string]string `json:"applied"`
+
+	// Previous maps the same variables to their state entries before the directory was entered,
+	// or to null if they were not set.
+	Previous map[string]*StateEntry `json:"previous"`
+
+	// StateFile is the path of the state file the values were written to, see StateFile.
+	StateFile string `json:"state_file,omitempty"`
+}
+
+// DirSwitch is the outcome of switching to the directory values of the working directory.
+type DirSwitch struct {
+	// Applied are the assignments written to the state file that were not applied before.
+	Applied []Assignment
+
+	// Restored maps the variables that were restored to the values they had before entering the directory.
+	Restored map[string]string
+
+	// Unset are the variables that were not set before entering the directory.
+	// They are removed from the state file and must be unset in the shell.
+	Unset []string
+}
+
+// Changed reports whether the switch changed any variable.
+func (s DirSwitch) Changed() bool {
+	return len(s.Applied) > 0 || len(s.Restored) > 0 || len(s.Unset) > 0
+}
+
+// DirStateFile returns the path of the directory state file in the home directory of the root.
+func DirStateFile(root *Root) string {
+	return root.HomePath(DirStateFileName)
+}
+
+// SwitchDirValues switches the state file of the root to the assignments of the directory values,
+// usually the ones of the working directory, see Config.ResolveDirValues.
+//
+// Variables of earlier directory values that are not part of the assignments are restored to the values
+// they had before, unless they were changed since. Switching to no assignments leaves the directory.
+// Earlier values are restored in the state file they were written to, even if the root has another state file now,
+// e.g. after leaving a project with its own state file.
+func SwitchDirValues(root *Root, assignments []Assignment) (DirSwitch, error) {
+	result := DirSwitch{Restored: make(map[string]string)}
+
+	// the directory state must not change between reading and rewriting it
+	unlock, err := lockFile(root.Fs, DirStateFile(root))
+	if err != nil {
+		return result, err
+	}
+	defer unlock()
+
+	state, err := readDirState(root)
+	if err != nil {
+		return result, err
+	}
+
+	if state.StateFile != "" && state.StateFile != StateFile(root) {
+		previous := *root
+		previous.StatePath = state.StateFile
+		if err := switchState(&previous, state, nil, &result); err != nil {
+			return result, err
+		}
+
+		// variables set again in the state file of the root stay set in the shell
+		result.Unset = slices.DeleteFunc(result.Unset, func(target string) bool {
+			return slices.ContainsFunc(assignments, func(a Assignment) bool { return a.TargetVar == target })
+		})
+	}
+
+	if err := switchState(root, state, assignments, &result); err != nil {
+		return result, err
+	}
+	state.StateFile = StateFile(root)
+
+	return result, writeDirState(root, state)
+}
+
+// switchState switches the state file of the root to the assignments, updating the directory state and the result.
+func switchState(root *Root, state *dirState, assignments []Assignment, result *DirSwitch) error {
+	// the state file must not change between reading and rewriting it
+	unlock, err := lockFile(root.Fs, sidecarPath(root))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := LoadState(root)
+	if err != nil {
+		return err
+	}
+
+	wanted := make(map[string]string, len(assignments))
+	for _, assignment := range assignments {
+		wanted[assignment.TargetVar] = assignment.Value
+	}
+
+	var entries []StateEntry
+	var unset []string
+
+	// restore the variables of the directory that was left
+	for _, target := range sortedKeys(state.Applied) {
+		if _, ok := wanted[target]; ok {
+			continue
+		}
+
+		applied, previous := state.Applied[target], state.Previous[target]
+		delete(state.Applied, target)
+		delete(state.Previous, target)
+
+		// values picked since entering the directory are kept
+		if entry, ok := current.Entry(target); !ok || entry.Value != applied {
+			continue
+		}
+
+		if previous == nil {
+			unset = append(unset, target)
+		} else {
+			// the restored value keeps where it came from, but is set again now
+			restored := *previous
+			restored.Time = time.Time{}
+			result.Restored[target] = restored.Value
+			entries = append(entries, restored)
+		}
+	}
+
+	// apply the variables of the directory that was entered
+	for _, assignment := range assignments {
+		target := assignment.TargetVar
+
+		applied, ok := state.Applied[target]
+		if ok && applied == assignment.Value {
+			continue
+		}
+		if !ok {
+			if entry, set := current.Entry(target); set {
+				state.Previous[target] = &entry
+			} else {
+				state.Previous[target] = nil
+			}
+		}
+
+		state.Applied[target] = assignment.Value
+		entries = append(entries, assignment.entry(SourceDirectory))
+		result.Applied = append(result.Applied, assignment)
+	}
+
+	result.Unset = append(result.Unset, unset...)
+	if len(entries) == 0 && len(unset) == 0 {
+		return nil
+	}
+
+	return rewriteState(root, stateUpdate{entries: entries, unset: unset, source: SourceDirectory})
+}
+
+// readDirState reads the directory state file of the root. A missing file results in an empty state.
+func readDirState(root *Root) (*dirState, error) {
+	state := &dirState{Applied: make(map[string]string), Previous: make(map[string]*StateEntry)}
+
+	data, err := afero.ReadFile(root.Fs, DirStateFile(root))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return state, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		// a broken state only loses the values to restore, so it must not block switching
+		slog.Warn("Ignoring invalid directory state file", "path", DirStateFile(root), "err", err)
+		return &dirState{Applied: make(map[string]string), Previous: make(map[string]*StateEntry)}, nil
+	}
+	if state.Applied == nil {
+		state.Applied = make(map[string]string)
+	}
+	if state.Previous == nil {
+		state.Previous = make(map[string]*StateEntry)
+	}
+
+	return state, nil
+}
+
+// writeDirState writes the directory state file of the root, or removes it if no directory values are applied.
+func writeDirState(root *Root, state *dirState) error {
+	path := DirStateFile(root)
+
+	if len(state.Applied) == 0 {
+		if err := root.Fs.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(root.Fs, path, append(data, '\n'), 0600)
+}
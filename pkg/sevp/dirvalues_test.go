@@ -0,0 +1,227 @@
+package sevp
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dirValuesTestRoot returns a root with a user config and a repository declaring directory values
+func dirValuesTestRoot(t *testing.T) *Root {
+	t.Helper()
+	t.Setenv("SEVP_SYSTEM_CONFIG", "")
+	t.Setenv("SEVP_TEAM_CONFIG", "")
+
+	root := newTestRoot()
+	writeTestFiles(t, root, map[string]string{
+		"/home/test/.config/sevp.toml": `
+[kube]
+target_var = "KUBECONFIG"
+possible_values = ["dev", "prod"]
+value_template = "/kube/{{ .Value }}.yaml"
+
+[region]
+target_var = "AWS_REGION"
+possible_values = ["eu-west-1", "us-east-1"]
+`,
+		"/home/test/repo/.git/HEAD": "",
+		"/home/test/repo/.sevp.toml": `
+[values]
+kube = "dev"
+region = "eu-west-1"
+`,
+		"/home/test/repo/prod/.sevp-values": `kube = "prod"` + "\n",
+	})
+
+	return root
+}
+
+// Directory values should be layered from the outermost to the innermost directory
+func TestDirValues(t *testing.T) {
+	root := dirValuesTestRoot(t)
+	root.Workdir = "/home/test/repo/prod"
+
+	cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"kube": "prod", "region": "eu-west-1"}, cfg.DirValues())
+
+	assignments, err := cfg.ResolveDirValues(root)
+	require.NoError(t, err)
+	assert.Equal(t, []Assignment{
+		{Selector: "kube", TargetVar: "KUBECONFIG", Item: "prod", Value: "/kube/prod.yaml"},
+		{Selector: "region", TargetVar: "AWS_REGION", Item: "eu-west-1", Value: "eu-west-1"},
+	}, assignments)
+}
+
+// Directory values should only be applied from project configs and be checked when validating
+func TestDirValuesValidation(t *testing.T) {
+	root := dirValuesTestRoot(t)
+	root.Workdir = "/home/test/repo/prod"
+	writeTestFiles(t, root, map[string]string{
+		"/home/test/.config/sevp.toml": `
+[kube]
+target_var = "KUBECONFIG"
+possible_values = ["dev", "prod"]
+
+[values]
+kube = "dev"
+`,
+		"/home/test/repo/prod/.sevp-values": "kube = \"staging\"\ngcp = \"acme\"\n",
+	})
+
+	cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	require.NoError(t, err)
+
+	var got []string
+	for _, diagnostic := range cfg.Validate() {
+		got = append(got, diagnostic.String())
+	}
+	assert.Equal(t, []string{
+		`/home/test/.config/sevp.toml:7: values.kube: values are only applied from project configs, ignoring`,
+		`/home/test/repo/.sevp.toml:4: values.region: selector "region" is not defined`,
+		`/home/test/repo/prod/.sevp-values:1: values.kube: "staging" is not a possible value of selector kube`,
+		`/home/test/repo/prod/.sevp-values:2: values.gcp: selector "gcp" is not defined`,
+	}, got)
+
+	_, err = cfg.ResolveDirValues(root)
+	assert.ErrorContains(t, err, "directory values:")
+}
+
+// Directory values should not pick selectors defined by the project itself
+func TestDirValuesProjectSelector(t *testing.T) {
+	root := dirValuesTestRoot(t)
+	root.Workdir = "/home/test/repo"
+	writeTestFiles(t, root, map[string]string{
+		"/home/test/repo/.sevp.toml": `
+[evil]
+target_var = "FOO"
+possible_values = ["a;echo PWNED"]
+
+[values]
+evil = "a;echo PWNED"
+`,
+	})
+
+	cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	require.NoError(t, err)
+
+	_, err = cfg.ResolveDirValues(root)
+	assert.ErrorContains(t, err, "selector evil is defined in a project config")
+
+	var got []string
+	for _, diagnostic := range cfg.Validate() {
+		got = append(got, diagnostic.String())
+	}
+	assert.Contains(t, got, `/home/test/repo/.sevp.toml:7: values.evil: selector "evil" is defined in a project config, values can only pick selectors of the system, team or user config`)
+}
+
+// Switching directories should apply their values and restore the previous ones when leaving
+func TestSwitchDirValues(t *testing.T) {
+	root := dirValuesTestRoot(t)
+	writeTestFiles(t, root, map[string]string{StateFile(root): "export KUBECONFIG=/kube/mine.yaml\n"})
+
+	resolve := func(workdir string) []Assignment {
+		root.Workdir = workdir
+		cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+		require.NoError(t, err)
+		assignments, err := cfg.ResolveDirValues(root)
+		require.NoError(t, err)
+		return assignments
+	}
+
+	// entering the repository
+	result, err := SwitchDirValues(root, resolve("/home/test/repo"))
+	require.NoError(t, err)
+	assert.Len(t, result.Applied, 2)
+	state, err := ReadState(root)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"KUBECONFIG": "/kube/dev.yaml", "AWS_REGION": "eu-west-1"}, state)
+
+	// staying in it changes nothing
+	result, err = SwitchDirValues(root, resolve("/home/test/repo"))
+	require.NoError(t, err)
+	assert.False(t, result.Changed())
+
+	// entering a subdirectory only changes its values
+	result, err = SwitchDirValues(root, resolve("/home/test/repo/prod"))
+	require.NoError(t, err)
+	require.Len(t, result.Applied, 1)
+	assert.Equal(t, "/kube/prod.yaml", result.Applied[0].Value)
+
+	// values picked by hand are kept when leaving
+	require.NoError(t, WriteToFile(root, "us-east-1", "AWS_REGION"))
+
+	// leaving the repository restores the values from before entering it
+	result, err = SwitchDirValues(root, resolve("/home/test"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"KUBECONFIG": "/kube/mine.yaml"}, result.Restored)
+	assert.Empty(t, result.Unset)
+	state, err = ReadState(root)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"KUBECONFIG": "/kube/mine.yaml", "AWS_REGION": "us-east-1"}, state)
+
+	exists, err := afero.Exists(root.Fs, DirStateFile(root))
+	require.NoError(t, err)
+	assert.False(t, exists, "the directory state should be removed once no values are applied")
+}
+
+// Values should be restored in the state file they were written to, even if the state file changed since
+func TestSwitchDirValuesStateFile(t *testing.T) {
+	root := dirValuesTestRoot(t)
+	root.Workdir = "/home/test/repo"
+	writeTestFiles(t, root, map[string]string{StateFile(root): "export KUBECONFIG=/kube/mine.yaml\n"})
+
+	cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	require.NoError(t, err)
+	assignments, err := cfg.ResolveDirValues(root)
+	require.NoError(t, err)
+
+	// the values of the repository are written to its own state file
+	project := *root
+	project.StatePath = "/home/test/repo/.envrc.local"
+	_, err = SwitchDirValues(&project, assignments)
+	require.NoError(t, err)
+
+	// leaving it for a directory with values of another state file restores the repository's state file
+	result, err := SwitchDirValues(root, []Assignment{{Selector: "region", TargetVar: "AWS_REGION", Item: "us-east-1", Value: "us-east-1"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"KUBECONFIG"}, result.Unset, "variables set again are not unset")
+	require.Len(t, result.Applied, 1)
+
+	state, err := ReadState(&project)
+	require.NoError(t, err)
+	assert.Empty(t, state, "the values are removed from the state file they were written to")
+
+	state, err = ReadState(root)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"KUBECONFIG": "/kube/mine.yaml", "AWS_REGION": "us-east-1"}, state)
+
+	// leaving that directory restores the state file of the root
+	result, err = SwitchDirValues(root, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"AWS_REGION"}, result.Unset)
+}
+
+// Variables that were not set before entering a directory should be unset when leaving it
+func TestSwitchDirValuesUnset(t *testing.T) {
+	root := dirValuesTestRoot(t)
+	root.Workdir = "/home/test/repo"
+
+	cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	require.NoError(t, err)
+	assignments, err := cfg.ResolveDirValues(root)
+	require.NoError(t, err)
+
+	_, err = SwitchDirValues(root, assignments)
+	require.NoError(t, err)
+
+	result, err := SwitchDirValues(root, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"AWS_REGION", "KUBECONFIG"}, result.Unset)
+
+	state, err := ReadState(root)
+	require.NoError(t, err)
+	assert.Empty(t, state)
+}
@@ -0,0 +1,38 @@
+// Package sevp is the library behind the sevp CLI.
+//
+// It exposes everything needed to embed sevp into another tool:
+//   - the filesystem and home directory sevp operates in (Root, OSRoot, SandboxRoot, Root.OSPath)
+//   - config loading and selectors (ConfigPath, InitConfig, LoadConfig, ReadConfig, FindProjectConfigs, Config, Options)
+//   - config file formats (Format, FormatOf, ReadConfigFormat, ConvertConfig)
+//   - themes of the TUI and the CLI output (Theme, ThemePresets)
+//   - key bindings of the TUI (Keys, KeyPresets)
+//   - config layers and merge rules (Layer, ConfigFile, SystemConfigPath, TeamConfigPath)
+//   - config validation and its JSON schema (Config.Validate, Diagnostic, JSONSchema)
+//   - editing config files in place (UpdateConfigFile, AddSelector, AddValue, RemoveSelector)
+//   - external config providers (Providers, RegisterProvider, GetExternalConfigSelector)
+//   - rendering the written values from picked items (RenderValue, ValueRenderer, TemplateData)
+//   - describing picked items with metadata (ItemMetadata, MetadataProvider)
+//   - resolving picked items and marking dangerous ones (Config.Resolve, Config.IsDangerous, Config.IsDangerousValue, Config.Restores, ConfigSelector.IsDangerous)
+//   - sets of selectors switched at once (Config.ResolveSet, Config.ApplySet, Assignment)
+//   - directory values applied when entering a project (Config.DirValues, Config.ResolveDirValues, SwitchDirValues, DirSwitch)
+//   - allowing the directory values of project files (AllowFile, AllowProjectFiles, DenyProjectFiles, BlockedProjectFiles)
+//   - reading and writing the state file (StateFile, MetadataFile, LoadState, State, StateEntry, ReadState, WriteToFile, WriteValues, WriteAssignments, UnsetValues, UnsetValuesFrom)
+//   - the history of changes and reverting them (ReadHistory, HistoryEntry, HistoryFilter, Undo, Back, RevertCheck)
+//   - ranking picked items by recent use and pins (LoadUsage, Usage, Rank, RankedItem, RecordUse, TogglePin, UsageFile)
+//   - direnv integration (Envrc, DirenvStdlib)
+//   - rendering shell hooks (Hook)
+//
+// # Compatibility
+//
+// The API of this package is versioned with semantic versioning through
+// APIVersion, independently of the CLI release version:
+//   - patch releases only contain fixes and never change the exported API
+//   - minor releases may add new identifiers
+//   - breaking changes to exported identifiers require a new major version
+//
+// While APIVersion is 0.x, minor releases may also contain breaking changes.
+// Identifiers slated for removal are marked as Deprecated first.
+package sevp
+
+// APIVersion is the semantic version of the exported API of this package.
+const APIVersion = "0.2.0"
@@ -0,0 +1,506 @@
+package sevp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2/unstable"
+	"github.com/spf13/afero"
+)
+
+// selectorNamePattern matches selector names that can be written as bare TOML keys.
+// Upper case letters are not allowed, as keys are case insensitive when the config is read.
+var selectorNamePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// The functions below edit TOML config documents in place rather than re-encoding them,
+// so comments, ordering and formatting of the rest of the document are preserved.
+
+// UpdateConfigFile applies the edit to the contents of the config file at the given path of the root.
+//
+// A missing file is edited as an empty document and created. The edited document must still be
+// a readable config, otherwise it is not written and an error is returned.
+func UpdateConfigFile(root *Root, configPath string, edit func(data []byte) ([]byte, error)) error {
+	configPath = filepath.Clean(configPath)
+
+	perm := os.FileMode(0600)
+	data, err := afero.ReadFile(root.Fs, configPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if err := root.Fs.MkdirAll(filepath.Dir(configPath), 0750); err != nil {
+			return fmt.Errorf("failed to create config directory: %w", err)
+		}
+	case err != nil:
+		return err
+	default:
+		if info, err := root.Fs.Stat(configPath); err == nil {
+			perm = info.Mode().Perm()
+		}
+	}
+
+	updated, err := edit(data)
+	if err != nil {
+		return err
+	}
+
+	if _, err := ReadConfigFormat(bytes.NewReader(updated), FormatOf(configPath)); err != nil && !errors.Is(err, errEmptyConfig) {
+		return fmt.Errorf("edited config is invalid: %w", err)
+	}
+
+	return writeFileAtomic(root.Fs, configPath, updated, perm)
+}
+
+// AddSelector returns the config document with a table for the selector appended to it.
+//
+// External selectors are written with external_config only, all others need a target_var
+// and at least one possible value.
+func AddSelector(data []byte, s *ConfigSelector) ([]byte, error) {
+	if !selectorNamePattern.MatchString(s.Name) {
+		return nil, fmt.Errorf("invalid selector name %q: only lowercase letters, digits, '-' and '_' are allowed", s.Name)
+	}
+	if slices.Contains(reservedKeys, s.Name) {
+		return nil, fmt.Errorf("invalid selector name %q: the name is reserved (reserved names: %s)", s.Name, strings.Join(reservedKeys, ", "))
+	}
+
+	if !s.ReadExternalConfig && !envVarPattern.MatchString(s.TargetVar) {
+		return nil, fmt.Errorf("invalid target_var %q: not a valid environment variable name", s.TargetVar)
+	}
+
+	if err := checkSelector(s); err != nil {
+		return nil, err
+	}
+
+	cfg, err := readDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := cfg.sections[s.Name]; ok {
+		return nil, fmt.Errorf("selector %q already exists", s.Name)
+	}
+
+	var b bytes.Buffer
+	b.Write(data)
+
+	// separate the new table from the existing document by a blank line
+	if len(bytes.TrimSpace(data)) > 0 {
+		if !bytes.HasSuffix(data, []byte("\n")) {
+			b.WriteString("\n")
+		}
+		if !bytes.HasSuffix(data, []byte("\n\n")) {
+			b.WriteString("\n")
+		}
+	}
+
+	fmt.Fprintf(&b, "[%s]\n", s.Name)
+	if s.ReadExternalConfig {
+		b.WriteString("external_config = true\n")
+	} else {
+		fmt.Fprintf(&b, "target_var = %s\n", tomlString(s.TargetVar))
+		fmt.Fprintf(&b, "possible_values = %s\n", tomlStringArray(s.PossibleValues))
+	}
+
+	return b.Bytes(), nil
+}
+
+// AddValue returns the config document with the value appended to the possible values of the named selector.
+func AddValue(data []byte, name string, value string) ([]byte, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, errors.New("value is empty")
+	}
+
+	cfg, err := readDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	sec, ok := cfg.sections[name]
+	if !ok {
+		return nil, fmt.Errorf("selector %q does not exist", name)
+	}
+
+	s, err := sec.decode(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %s - %w", name, err)
+	}
+	if s.ReadExternalConfig {
+		return nil, fmt.Errorf("selector %q reads its values from an external config", name)
+	}
+	if slices.Contains(s.PossibleValues, value) {
+		return nil, fmt.Errorf("selector %q already has the value %q", name, value)
+	}
+
+	table, err := findTable(data, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// without possible values, add the key at the end of the table
+	keyOffset, ok := table.keys["possible_values"]
+	if !ok {
+		line := "possible_values = " + tomlStringArray([]string{value}) + "\n"
+		if table.end > 0 && data[table.end-1] != '\n' {
+			line = "\n" + line
+		}
+		return splice(data, table.end, table.end, line), nil
+	}
+
+	open, closing, last, err := findArray(data, keyOffset)
+	if err != nil {
+		return nil, fmt.Errorf("possible_values of %q: %w", name, err)
+	}
+
+	quoted := tomlString(value)
+
+	// single line arrays get the value appended in line
+	if !bytes.Contains(data[open:closing], []byte("\n")) {
+		switch data[last] {
+		case '[':
+			return splice(data, last+1, last+1, quoted), nil
+		case ',':
+			return splice(data, last+1, last+1, " "+quoted+","), nil
+		default:
+			return splice(data, last+1, last+1, ", "+quoted), nil
+		}
+	}
+
+	// multiline arrays get the value on a new line, indented like the previous value
+	indent := lineIndent(data, keyOffset) + "  "
+	if data[last] != '[' {
+		indent = lineIndent(data, last)
+	}
+
+	comma := ""
+	if data[last] != '[' && data[last] != ',' {
+		comma = ","
+	}
+
+	closingLine := lineStart(data, closing)
+	if len(bytes.TrimSpace(data[closingLine:closing])) == 0 {
+		data = splice(data, closingLine, closingLine, indent+quoted+",\n")
+		return splice(data, last+1, last+1, comma), nil
+	}
+
+	return splice(data, last+1, last+1, comma+"\n"+indent+quoted), nil
+}
+
+// RemoveSelector returns the config document without the table of the named selector and its subtables,
+// e.g. the tables of the metadata of its values. If the selector is the default selector, default is removed as well,
+// so it doesn't name a missing selector.
+//
+// Comments directly above a table header are removed with it. Comments and blank lines
+// following a table are kept, as they usually belong to what comes next.
+func RemoveSelector(data []byte, name string) ([]byte, error) {
+	if _, err := findTable(data, name); err != nil {
+		return nil, err
+	}
+
+	tables, err := findTables(data, func(path string, array bool) bool {
+		return path == name || strings.HasPrefix(path, name+".")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// remove the tables from the last to the first, so the offsets of the others stay valid
+	for i := len(tables) - 1; i >= 0; i-- {
+		data = removeTable(data, tables[i])
+	}
+
+	// top-level keys precede all tables, so removing the tables kept the offset of default
+	start, end, value, err := findDefault(data)
+	if err != nil {
+		return nil, err
+	}
+	if start >= 0 && strings.EqualFold(value, name) {
+		data = splice(data, start, end, "")
+	}
+
+	return data, nil
+}
+
+// findDefault finds the top-level default key of the TOML document. It returns the offsets of the line of the key
+// and after its line break, and its value, or -1 for both offsets if the document has no default.
+func findDefault(data []byte) (start int, end int, value string, err error) {
+	p := unstable.Parser{}
+	p.Reset(data)
+
+	for p.NextExpression() {
+		e := p.Expression()
+
+		switch e.Kind {
+		case unstable.Table, unstable.ArrayTable:
+			return -1, -1, "", nil
+		case unstable.KeyValue:
+			it := e.Key()
+			it.Next()
+			key := it.Node()
+			if strings.ToLower(string(key.Data)) != "default" || it.Next() {
+				continue
+			}
+
+			offset := p.Shape(key.Raw).Start.Offset
+			return lineStart(data, offset), lineEnd(data, offset), string(e.Value().Data), nil
+		}
+	}
+
+	return -1, -1, "", p.Error()
+}
+
+// removeTable returns the document without the table and the comments directly above its header.
+func removeTable(data []byte, table *tomlTable) []byte {
+	start := table.start
+	for start > 0 {
+		prev := lineStart(data, start-1)
+		if !bytes.HasPrefix(bytes.TrimSpace(data[prev:start]), []byte("#")) {
+			break
+		}
+		start = prev
+	}
+
+	before, after := data[:start], data[table.end:]
+
+	// don't leave a gap of two blank lines behind
+	if len(bytes.TrimSpace(before)) == 0 || bytes.HasSuffix(before, []byte("\n\n")) {
+		after = bytes.TrimLeft(after, "\n")
+	}
+
+	return append(slices.Clone(before), after...)
+}
+
+// readDocument reads a config document, treating an empty document as an empty config.
+func readDocument(data []byte) (*Config, error) {
+	cfg, err := ReadConfig(bytes.NewReader(data))
+	if errors.Is(err, errEmptyConfig) {
+		return &Config{sections: make(map[string]*section)}, nil
+	}
+
+	return cfg, err
+}
+
+// tomlTable is the location of a table in a TOML document.
+type tomlTable struct {
+	// start is the offset of the line the table header is on.
+	start int
+
+	// end is the offset after the last key of the table, including its line break.
+	end int
+
+	// keys maps the keys of the table to their offsets.
+	keys map[string]int
+}
+
+// findTable finds the table of the named selector in the TOML document.
+func findTable(data []byte, name string) (*tomlTable, error) {
+	tables, err := findTables(data, func(path string, array bool) bool {
+		return !array && path == name
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("selector %q is not defined as a table in the config file", name)
+	}
+
+	return tables[0], nil
+}
+
+// findTables finds the tables and array tables of the TOML document whose dotted, lower case key path matches,
+// in the order of the document.
+func findTables(data []byte, match func(path string, array bool) bool) ([]*tomlTable, error) {
+	p := unstable.Parser{}
+	p.Reset(data)
+
+	var tables []*tomlTable
+	var table *tomlTable
+	lastKeyEnd := 0
+
+	for p.NextExpression() {
+		e := p.Expression()
+
+		switch e.Kind {
+		case unstable.Table, unstable.ArrayTable:
+			if table != nil {
+				table.end = lastKeyEnd
+				table = nil
+			}
+
+			var path []string
+			var offset int
+			for it := e.Key(); it.Next(); {
+				if path == nil {
+					offset = p.Shape(it.Node().Raw).Start.Offset
+				}
+				path = append(path, strings.ToLower(string(it.Node().Data)))
+			}
+
+			if match(strings.Join(path, "."), e.Kind == unstable.ArrayTable) {
+				table = &tomlTable{start: lineStart(data, offset), keys: make(map[string]int)}
+				tables = append(tables, table)
+				lastKeyEnd = lineEnd(data, offset)
+			}
+		case unstable.KeyValue:
+			it := e.Key()
+			it.Next()
+			offset := p.Shape(it.Node().Raw).Start.Offset
+
+			if table != nil {
+				table.keys[strings.ToLower(string(it.Node().Data))] = offset
+				lastKeyEnd = keyValueEnd(data, offset)
+			}
+		}
+	}
+
+	if err := p.Error(); err != nil {
+		return nil, err
+	}
+
+	if table != nil {
+		table.end = lastKeyEnd
+	}
+
+	return tables, nil
+}
+
+// keyValueEnd returns the offset after the line break ending the key-value pair starting at the offset.
+func keyValueEnd(data []byte, offset int) int {
+	eq := bytes.IndexByte(data[offset:], '=')
+	if eq < 0 {
+		return lineEnd(data, offset)
+	}
+
+	// arrays may span multiple lines
+	valueStart := offset + eq + 1
+	for valueStart < len(data) && (data[valueStart] == ' ' || data[valueStart] == '\t') {
+		valueStart++
+	}
+	if valueStart < len(data) && data[valueStart] == '[' {
+		if _, closing, _, err := findArray(data, offset); err == nil {
+			return lineEnd(data, closing)
+		}
+	}
+
+	return lineEnd(data, offset)
+}
+
+// findArray finds the array value of the key at the offset.
+//
+// It returns the offsets of the opening and closing brackets, and of the last
+// character within the array that is neither whitespace nor part of a comment.
+func findArray(data []byte, keyOffset int) (open int, closing int, last int, err error) {
+	eq := bytes.IndexByte(data[keyOffset:], '=')
+	if eq < 0 {
+		return 0, 0, 0, errors.New("missing value")
+	}
+
+	open = keyOffset + eq + 1
+	for open < len(data) && (data[open] == ' ' || data[open] == '\t') {
+		open++
+	}
+	if open >= len(data) || data[open] != '[' {
+		return 0, 0, 0, errors.New("value is not an array")
+	}
+
+	last = open
+	depth := 0
+	for i := open; i < len(data); i++ {
+		switch c := data[i]; c {
+		case '"', '\'':
+			// skip strings, escapes only exist in basic strings
+			for i++; i < len(data) && data[i] != c; i++ {
+				if c == '"' && data[i] == '\\' {
+					i++
+				}
+			}
+			last = i
+		case '#':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+		case '[':
+			depth++
+			last = i
+		case ']':
+			depth--
+			if depth == 0 {
+				return open, i, last, nil
+			}
+			last = i
+		case ' ', '\t', '\r', '\n':
+		default:
+			last = i
+		}
+	}
+
+	return 0, 0, 0, errors.New("unterminated array")
+}
+
+// splice replaces data[start:end] with the text.
+func splice(data []byte, start int, end int, text string) []byte {
+	result := make([]byte, 0, len(data)+len(text))
+	result = append(result, data[:start]...)
+	result = append(result, text...)
+	return append(result, data[end:]...)
+}
+
+// lineStart returns the offset of the start of the line the offset is on.
+func lineStart(data []byte, offset int) int {
+	return bytes.LastIndexByte(data[:offset], '\n') + 1
+}
+
+// lineEnd returns the offset after the line break ending the line the offset is on.
+func lineEnd(data []byte, offset int) int {
+	if i := bytes.IndexByte(data[offset:], '\n'); i >= 0 {
+		return offset + i + 1
+	}
+
+	return len(data)
+}
+
+// lineIndent returns the leading whitespace of the line the offset is on.
+func lineIndent(data []byte, offset int) string {
+	start := lineStart(data, offset)
+	line := data[start:lineEnd(data, offset)]
+
+	return string(line[:len(line)-len(bytes.TrimLeft(line, " \t"))])
+}
+
+// tomlString quotes the string as a TOML basic string.
+func tomlString(s string) string {
+	var b strings.Builder
+
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+// tomlStringArray formats the strings as an inline TOML array.
+func tomlStringArray(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, tomlString(value))
+	}
+
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
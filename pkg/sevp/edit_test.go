@@ -0,0 +1,237 @@
+package sevp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const editTestConfig = `# my config
+default = "aws"
+
+[aws]
+external_config = true # read ~/.aws/config
+
+# kubernetes clusters
+[kube]
+target_var = "KUBECONFIG"
+possible_values = [
+  "dev", # development
+  "prod"
+]
+
+# [goenv]
+# external_config = true
+
+[region]
+target_var = "AWS_REGION"
+possible_values = ["eu-west-1"]
+`
+
+// Adding a selector should append a table and keep the rest of the document as is
+func TestAddSelector(t *testing.T) {
+	data, err := AddSelector([]byte(editTestConfig), &ConfigSelector{
+		Name:           "node",
+		TargetVar:      "NODE_ENV",
+		PossibleValues: []string{"development", `say "hi"`},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, editTestConfig+`
+[node]
+target_var = "NODE_ENV"
+possible_values = ["development", "say \"hi\""]
+`, string(data))
+
+	data, err = AddSelector(nil, &ConfigSelector{Name: "goenv", ReadExternalConfig: true})
+	require.NoError(t, err)
+	assert.Equal(t, "[goenv]\nexternal_config = true\n", string(data))
+
+	tests := []struct {
+		name     string
+		selector *ConfigSelector
+		err      string
+	}{
+		{"Existing selector", &ConfigSelector{Name: "aws", ReadExternalConfig: true}, `selector "aws" already exists`},
+		{"Invalid name", &ConfigSelector{Name: "My Selector", ReadExternalConfig: true}, "invalid selector name"},
+		{"Reserved name", &ConfigSelector{Name: "sets", ReadExternalConfig: true}, `invalid selector name "sets": the name is reserved`},
+		{"Invalid target var", &ConfigSelector{Name: "x", TargetVar: "1X", PossibleValues: []string{"a"}}, "invalid target_var"},
+		{"No values", &ConfigSelector{Name: "x", TargetVar: "X"}, "possible_values"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := AddSelector([]byte(editTestConfig), test.selector)
+			assert.ErrorContains(t, err, test.err)
+		})
+	}
+}
+
+// Adding a value should extend the array of possible values in its own style
+func TestAddValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{
+			name:     "Single line",
+			config:   "[a]\ntarget_var = \"A\"\npossible_values = [\"x\"] # values\n",
+			expected: "[a]\ntarget_var = \"A\"\npossible_values = [\"x\", \"new\"] # values\n",
+		},
+		{
+			name:     "Single line with trailing comma",
+			config:   "[a]\ntarget_var = \"A\"\npossible_values = [\"x\",]\n",
+			expected: "[a]\ntarget_var = \"A\"\npossible_values = [\"x\", \"new\",]\n",
+		},
+		{
+			name:     "Empty array",
+			config:   "[a]\ntarget_var = \"A\"\npossible_values = []\n",
+			expected: "[a]\ntarget_var = \"A\"\npossible_values = [\"new\"]\n",
+		},
+		{
+			name:     "Multiline",
+			config:   "[a]\ntarget_var = \"A\"\npossible_values = [\n    \"x\", # first\n    \"y\" # second\n]\n",
+			expected: "[a]\ntarget_var = \"A\"\npossible_values = [\n    \"x\", # first\n    \"y\", # second\n    \"new\",\n]\n",
+		},
+		{
+			name:     "Multiline with closing bracket after value",
+			config:   "[a]\ntarget_var = \"A\"\npossible_values = [\"x\",\n  \"y\"]\n",
+			expected: "[a]\ntarget_var = \"A\"\npossible_values = [\"x\",\n  \"y\",\n  \"new\"]\n",
+		},
+		{
+			name:     "Missing key",
+			config:   "[a]\ntarget_var = \"A\"\n\n[b]\n",
+			expected: "[a]\ntarget_var = \"A\"\npossible_values = [\"new\"]\n\n[b]\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := AddValue([]byte(test.config), "a", "new")
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, string(data))
+		})
+	}
+
+	for value, expected := range map[string]string{
+		"prod":   `already has the value "prod"`,
+		"  ":     "value is empty",
+		"eu-x-1": "",
+	} {
+		_, err := AddValue([]byte(editTestConfig), "kube", value)
+		if expected == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.ErrorContains(t, err, expected)
+		}
+	}
+
+	_, err := AddValue([]byte(editTestConfig), "aws", "x")
+	assert.ErrorContains(t, err, "external config")
+
+	_, err = AddValue([]byte(editTestConfig), "nope", "x")
+	assert.ErrorContains(t, err, `selector "nope" does not exist`)
+
+	_, err = AddValue([]byte("a.target_var = \"A\"\na.possible_values = [\"x\"]\n"), "a", "y")
+	assert.ErrorContains(t, err, "not defined as a table")
+}
+
+// Removing a selector should remove its table and its comment but keep the comments following it
+func TestRemoveSelector(t *testing.T) {
+	data, err := RemoveSelector([]byte(editTestConfig), "kube")
+	require.NoError(t, err)
+	assert.Equal(t, `# my config
+default = "aws"
+
+[aws]
+external_config = true # read ~/.aws/config
+
+# [goenv]
+# external_config = true
+
+[region]
+target_var = "AWS_REGION"
+possible_values = ["eu-west-1"]
+`, string(data))
+
+	data, err = RemoveSelector([]byte(editTestConfig), "region")
+	require.NoError(t, err)
+	assert.Equal(t, editTestConfig[:len(editTestConfig)-len("[region]\ntarget_var = \"AWS_REGION\"\npossible_values = [\"eu-west-1\"]\n")], string(data))
+
+	_, err = RemoveSelector([]byte(editTestConfig), "nope")
+	assert.ErrorContains(t, err, `selector "nope" is not defined`)
+
+	// removing the default selector removes the default as well
+	data, err = RemoveSelector([]byte(editTestConfig), "aws")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "# my config\n\n# kubernetes clusters\n[kube]\n"), string(data))
+	cfg, err := ReadConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Default)
+
+	// subtables of the selector are removed with it, but not tables of selectors sharing its prefix
+	data, err = RemoveSelector([]byte(`[gcp]
+target_var = "CLOUDSDK_CORE_PROJECT"
+possible_values = ["acme"]
+value_template = "{{ .Meta.project }}"
+
+[gcp-dev]
+target_var = "CLOUDSDK_CONFIG"
+possible_values = ["dev"]
+
+# project ids
+[gcp.meta]
+acme = { project = "acme-prod-1234" }
+
+[gcp.meta.acme.labels]
+team = "platform"
+`), "gcp")
+	require.NoError(t, err)
+	assert.Equal(t, `[gcp-dev]
+target_var = "CLOUDSDK_CONFIG"
+possible_values = ["dev"]
+
+`, string(data))
+
+	cfg, err = ReadConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gcp-dev"}, cfg.Names())
+}
+
+// Updating a config file should only write valid configs and keep the file mode
+func TestUpdateConfigFile(t *testing.T) {
+	root := newTestRoot()
+
+	err := UpdateConfigFile(root, "/home/test/.config/sevp.toml", func(data []byte) ([]byte, error) {
+		return AddSelector(data, &ConfigSelector{Name: "aws", ReadExternalConfig: true})
+	})
+	require.NoError(t, err)
+
+	content, err := afero.ReadFile(root.Fs, "/home/test/.config/sevp.toml")
+	require.NoError(t, err)
+	assert.Equal(t, "[aws]\nexternal_config = true\n", string(content))
+
+	require.NoError(t, root.Fs.Chmod("/home/test/.config/sevp.toml", 0640))
+	err = UpdateConfigFile(root, "/home/test/.config/sevp.toml", func(data []byte) ([]byte, error) {
+		return append(data, "[broken\n"...), nil
+	})
+	assert.ErrorContains(t, err, "edited config is invalid")
+
+	err = UpdateConfigFile(root, "/home/test/.config/sevp.toml", func(data []byte) ([]byte, error) {
+		return RemoveSelector(data, "aws")
+	})
+	require.NoError(t, err)
+
+	info, err := root.Fs.Stat("/home/test/.config/sevp.toml")
+	require.NoError(t, err)
+	assert.Equal(t, "-rw-r-----", info.Mode().Perm().String())
+	assert.Zero(t, info.Size())
+
+	files, err := afero.ReadDir(root.Fs, "/home/test/.config")
+	require.NoError(t, err)
+	assert.Len(t, files, 1, "the config is replaced atomically without leaving temporary files")
+}
@@ -0,0 +1,222 @@
+package sevp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// Options are the global options of the config, set in its [options] table.
+//
+// Options are merged key by key across all layers, just like selectors.
+type Options struct {
+	// AllowCommands enables $(command) substitution in config values.
+	// It is ignored in project configs, as those come with the repositories they are in.
+	AllowCommands bool `mapstructure:"allow_commands" description:"Allow $(command) substitution in config values. Ignored in project configs."`
+
+	// StateFile is the file picked values are written to instead of the state file in the home directory,
+	// e.g. .envrc.local for direnv. Relative paths are relative to the directory of the config file setting it.
+	// Project configs may only set files inside of their directory.
+	StateFile string `mapstructure:"state_file" description:"Write picked values to this file instead of ~/.sevp, e.g. .envrc.local for direnv. Relative to the config file."`
+}
+
+// decodeOptions decodes the raw values of the options table, rejecting unknown keys.
+func decodeOptions(raw map[string]any) (Options, error) {
+	var opts Options
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		ErrorUnused: true,
+		Result:      &opts,
+	})
+	if err != nil {
+		return opts, err
+	}
+
+	if err := decoder.Decode(raw); err != nil {
+		return opts, fmt.Errorf("invalid options: %w", err)
+	}
+
+	return opts, nil
+}
+
+// mergeOptions merges the raw options of another config into the options of the config.
+//
+// Keys already set are only replaced if override is set.
+func (c *Config) mergeOptions(raw map[string]any, override bool) {
+	if len(raw) == 0 {
+		return
+	}
+
+	if c.rawOptions == nil {
+		c.rawOptions = make(map[string]any)
+	}
+	for key, value := range raw {
+		if _, ok := c.rawOptions[key]; ok && !override {
+			continue
+		}
+		c.rawOptions[key] = value
+	}
+
+	// the options of every file were decoded successfully when it was read, so the merged ones decode as well
+	c.Options, _ = decodeOptions(c.rawOptions)
+}
+
+// expander expands references in config values:
+//
+//   - ${VAR} is replaced with the value of the environment variable VAR, which must be set
+//   - $(command) is replaced with the output of the command run by sh, if commands are allowed
+//   - $$ is a literal $
+//
+// Any other $ is kept as is.
+type expander struct {
+	// allowCommands enables $(command) substitution.
+	allowCommands bool
+
+	// dryRun checks the references without running commands, which are kept as is.
+	dryRun bool
+
+	// projectValues maps keys to the values that came from project configs, which never run commands.
+	projectValues map[string]map[string]bool
+
+	// project is set for values of project configs, see expandValue.
+	project bool
+}
+
+// expandSelector expands the target variable and the possible values of the selector in place.
+func (e expander) expandSelector(s *ConfigSelector) error {
+	targetVar, err := e.expandValue("target_var", s.TargetVar)
+	if err != nil {
+		return fmt.Errorf("target_var: %w", err)
+	}
+	s.TargetVar = targetVar
+
+	for i, value := range s.PossibleValues {
+		expanded, err := e.expandValue("possible_values", value)
+		if err != nil {
+			return fmt.Errorf("possible_values: %w", err)
+		}
+		s.PossibleValues[i] = expanded
+	}
+
+	return nil
+}
+
+// expandValue expands all references in the value of the key, refusing commands if the value came from a project config.
+func (e expander) expandValue(key string, value string) (string, error) {
+	if e.projectValues[key][value] {
+		e.allowCommands = false
+		e.project = true
+	}
+
+	return e.expand(value)
+}
+
+// expand expands all references in the value.
+func (e expander) expand(value string) (string, error) {
+	if !strings.Contains(value, "$") {
+		return value, nil
+	}
+
+	var b strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' || i+1 == len(value) {
+			b.WriteByte(value[i])
+			continue
+		}
+
+		switch value[i+1] {
+		case '$':
+			b.WriteByte('$')
+			i++
+		case '{':
+			end := strings.IndexByte(value[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated ${ in %q", value)
+			}
+
+			name := value[i+2 : i+end]
+			if !envVarPattern.MatchString(name) {
+				return "", fmt.Errorf("invalid variable name %q in %q", name, value)
+			}
+
+			envValue, ok := os.LookupEnv(name)
+			if !ok {
+				return "", fmt.Errorf("environment variable %s is not set", name)
+			}
+
+			b.WriteString(envValue)
+			i += end
+		case '(':
+			end := closingParen(value, i+1)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated $( in %q", value)
+			}
+
+			command := value[i+2 : end]
+			if e.project {
+				return "", fmt.Errorf("command substitution $(%s) is disabled in project configs", command)
+			}
+			if !e.allowCommands {
+				return "", fmt.Errorf("command substitution $(%s) is disabled, enable it with allow_commands = true in [options]", command)
+			}
+
+			if e.dryRun {
+				b.WriteString(value[i : end+1])
+			} else {
+				output, err := runCommand(command)
+				if err != nil {
+					return "", err
+				}
+				b.WriteString(output)
+			}
+			i = end
+		default:
+			b.WriteByte('$')
+		}
+	}
+
+	return b.String(), nil
+}
+
+// closingParen returns the index of the parenthesis closing the one at the index, or -1 if it is not closed.
+func closingParen(value string, open int) int {
+	depth := 0
+	for i := open; i < len(value); i++ {
+		switch value[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// runCommand runs the command with sh and returns its output without trailing line breaks.
+func runCommand(command string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	c := exec.Command("sh", "-c", command)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+
+	if err := c.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() > 0 {
+			return "", fmt.Errorf("command $(%s) failed: %s", command, strings.TrimSpace(stderr.String()))
+		}
+		return "", fmt.Errorf("command $(%s) failed: %w", command, err)
+	}
+
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
@@ -0,0 +1,241 @@
+package sevp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Expanding values should replace references to environment variables and commands
+func TestExpand(t *testing.T) {
+	t.Setenv("SEVP_TEST_USER", "alice")
+	t.Setenv("SEVP_TEST_EMPTY", "")
+
+	tests := []struct {
+		name     string
+		expander expander
+		value    string
+		expected string
+		err      string
+	}{
+		{name: "No references", value: "plain", expected: "plain"},
+		{name: "Variable", value: "/home/${SEVP_TEST_USER}/.kube", expected: "/home/alice/.kube"},
+		{name: "Empty variable", value: "a${SEVP_TEST_EMPTY}b", expected: "ab"},
+		{name: "Escaped dollar", value: "$${SEVP_TEST_USER}", expected: "${SEVP_TEST_USER}"},
+		{name: "Lone dollars", value: "$HOME $ cost$", expected: "$HOME $ cost$"},
+		{name: "Unset variable", value: "${SEVP_TEST_UNSET}", err: "environment variable SEVP_TEST_UNSET is not set"},
+		{name: "Unterminated variable", value: "${SEVP_TEST_USER", err: "unterminated ${"},
+		{name: "Invalid variable", value: "${1X}", err: `invalid variable name "1X"`},
+		{name: "Command not allowed", value: "$(echo hi)", err: "command substitution $(echo hi) is disabled"},
+		{name: "Command", expander: expander{allowCommands: true}, value: "$(echo $(echo hi))!\n", expected: "hi!\n"},
+		{name: "Command dry run", expander: expander{allowCommands: true, dryRun: true}, value: "$(exit 1)", expected: "$(exit 1)"},
+		{name: "Failing command", expander: expander{allowCommands: true}, value: "$(echo oops >&2; exit 1)", err: "failed: oops"},
+		{name: "Unterminated command", expander: expander{allowCommands: true}, value: "$(echo", err: "unterminated $("},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := test.expander.expand(test.value)
+			if test.err != "" {
+				assert.ErrorContains(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+// Selectors should be returned with their values expanded
+func TestFromConfigExpandsValues(t *testing.T) {
+	t.Setenv("SEVP_TEST_HOME", "/home/alice")
+
+	cfg, err := ReadConfig(strings.NewReader(`
+[options]
+allow_commands = true
+
+[kube]
+target_var = "KUBECONFIG"
+possible_values = ["${SEVP_TEST_HOME}/.kube/dev.yaml", "$(echo prod).yaml"]
+
+[broken]
+target_var = "X"
+possible_values = ["${SEVP_TEST_UNSET}"]
+
+[c]
+target_var = "C"
+possible_values = ["$HOME/.kube/a", "~/x", "$$PATH", "${HOME}/y", "a~b"]
+`))
+	require.NoError(t, err)
+	assert.True(t, cfg.Options.AllowCommands)
+
+	s, err := cfg.FromConfig("kube")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/home/alice/.kube/dev.yaml", "prod.yaml"}, s.PossibleValues)
+
+	_, err = cfg.FromConfig("broken")
+	assert.ErrorContains(t, err, "invalid selector: broken - possible_values: environment variable SEVP_TEST_UNSET is not set")
+	assert.NotContains(t, cfg.Names(), "options", "options is not a selector")
+
+	_, err = ReadConfig(strings.NewReader("[options]\nallow_command = true\n"))
+	assert.ErrorContains(t, err, "invalid options")
+}
+
+// Project configs should not be able to allow commands
+func TestAllowCommandsIgnoredInProjectConfigs(t *testing.T) {
+	root := newTestRoot()
+	root.Workdir = "/home/test/repo"
+	t.Setenv("SEVP_SYSTEM_CONFIG", "")
+	t.Setenv("SEVP_TEAM_CONFIG", "")
+
+	writeTestFiles(t, root, map[string]string{
+		"/home/test/.config/sevp.toml": `
+[kube]
+target_var = "KUBECONFIG"
+possible_values = ["dev"]
+`,
+		"/home/test/repo/.git/HEAD": "",
+		"/home/test/repo/.sevp.toml": `
+[options]
+allow_commands = true
+
+[kube]
+possible_values = ["$(touch /tmp/pwned)"]
+`,
+	})
+
+	cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	require.NoError(t, err)
+	assert.False(t, cfg.Options.AllowCommands)
+
+	_, err = cfg.FromConfig("kube")
+	assert.ErrorContains(t, err, "command substitution $(touch /tmp/pwned) is disabled")
+
+	diagnostics := cfg.Validate()
+	require.Len(t, diagnostics, 1)
+	assert.Equal(t, "/home/test/repo/.sevp.toml", diagnostics[0].File)
+	assert.Equal(t, 6, diagnostics[0].Line)
+	assert.Equal(t, "kube.possible_values", diagnostics[0].Key)
+}
+
+// Target variables that are not plain names should be rejected, as they are written to the state file the shell evaluates
+func TestInvalidTargetVar(t *testing.T) {
+	root := newTestRoot()
+	root.Workdir = "/home/test/repo"
+	t.Setenv("SEVP_SYSTEM_CONFIG", "")
+	t.Setenv("SEVP_TEAM_CONFIG", "")
+	t.Setenv("SEVP_TEST_SUFFIX", "1; touch /tmp/pwned")
+
+	writeTestFiles(t, root, map[string]string{
+		"/home/test/.config/sevp.toml": `
+[env]
+target_var = "VAR_${SEVP_TEST_SUFFIX}"
+possible_values = ["v"]
+`,
+		"/home/test/repo/.git/HEAD": "",
+		"/home/test/repo/.sevp.toml": `
+[evil]
+target_var = "A=1; touch /tmp/pwned; B"
+possible_values = ["v"]
+`,
+	})
+
+	cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	require.NoError(t, err)
+
+	_, err = cfg.FromConfig("evil")
+	assert.ErrorContains(t, err, "`target_var` \"A=1; touch /tmp/pwned; B\" is not a valid environment variable name")
+
+	_, err = cfg.FromConfig("env")
+	assert.ErrorContains(t, err, "`target_var` \"VAR_1; touch /tmp/pwned\" is not a valid environment variable name")
+
+	_, err = cfg.Resolve(root, "evil", "v")
+	assert.Error(t, err)
+}
+
+// Values of project configs should never run commands, even if the user config allows them
+func TestProjectValuesNeverRunCommands(t *testing.T) {
+	root := newTestRoot()
+	root.Workdir = "/home/test/repo"
+	t.Setenv("SEVP_SYSTEM_CONFIG", "")
+	t.Setenv("SEVP_TEAM_CONFIG", "")
+
+	dir := t.TempDir()
+	count := filepath.Join(dir, "count")
+	pwned := filepath.Join(dir, "pwned")
+
+	writeTestFiles(t, root, map[string]string{
+		"/home/test/.config/sevp.toml": `
+[options]
+allow_commands = true
+
+[kube]
+target_var = "KUBECONFIG"
+possible_values = ["$(echo run >> ` + count + `; echo dev)"]
+`,
+		"/home/test/repo/.git/HEAD": "",
+		"/home/test/repo/.sevp.toml": `
+[kube]
+merge = "append"
+possible_values = ["staging"]
+
+[evil]
+target_var = "X"
+possible_values = ["$(touch ` + pwned + `)ok"]
+`,
+	})
+
+	cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	require.NoError(t, err)
+	assert.True(t, cfg.Options.AllowCommands)
+
+	_, err = cfg.FromConfig("evil")
+	assert.ErrorContains(t, err, "is disabled in project configs")
+	assert.NoFileExists(t, pwned)
+
+	// commands of the user config still run, but only once
+	for range 3 {
+		s, err := cfg.FromConfig("kube")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"dev", "staging"}, s.PossibleValues)
+	}
+	runs, err := os.ReadFile(count)
+	require.NoError(t, err)
+	assert.Equal(t, "run\n", string(runs))
+}
+
+// Validating should report unresolved references, invalid expanded target variables and shell syntax that is exported literally
+func TestValidateExpansion(t *testing.T) {
+	t.Setenv("SEVP_TEST_PREFIX", "1")
+
+	cfg, err := ReadConfig(strings.NewReader(`
+[a]
+target_var = "${SEVP_TEST_PREFIX}_PROFILE"
+possible_values = ["x"]
+
+[b]
+target_var = "B"
+possible_values = ["${SEVP_TEST_UNSET}"]
+
+[c]
+target_var = "C"
+possible_values = ["$HOME/.kube/a", "~/x", "$$PATH", "${HOME}/y", "a~b"]
+`))
+	require.NoError(t, err)
+
+	var got []string
+	for _, diagnostic := range cfg.Validate() {
+		got = append(got, diagnostic.String())
+	}
+
+	assert.Equal(t, []string{
+		`a.target_var: "1_PROFILE" (expanded from "${SEVP_TEST_PREFIX}_PROFILE") is not a valid environment variable name`,
+		`b.possible_values: environment variable SEVP_TEST_UNSET is not set`,
+		`c.possible_values: "$HOME/.kube/a" is exported literally, the shell doesn't expand values; use ${HOME}`,
+		`c.possible_values: "~/x" is exported literally, the shell doesn't expand values; use ${HOME}`,
+	}, got)
+}
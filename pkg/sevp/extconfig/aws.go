@@ -0,0 +1,133 @@
+package extconfig
+
+import (
+	"io"
+	"log/slog"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// AWSProfileSelector is a struct that implements the Selector interface for selecting AWS profiles.
+type AWSProfileSelector struct {
+	fs   afero.Fs
+	home string
+}
+
+// Read is the main function of the AWSProfileSelector struct, which reads the AWS profile names from the AWS config file.
+func (s *AWSProfileSelector) Read() (string, []string, error) {
+	targetVar := "AWS_PROFILE"
+	profiles, err := getAWSProfiles(s.fs, s.home)
+	return targetVar, profiles, err
+}
+
+// NewAWSProfileSelector creates a new instance of AWSProfileSelector reading from the given filesystem and home directory.
+func NewAWSProfileSelector(fs afero.Fs, home string) *AWSProfileSelector {
+	return &AWSProfileSelector{fs: fs, home: home}
+}
+
+// Metadata returns the settings of the AWS profile in the AWS config file, e.g. its region and sso_account_id.
+func (s *AWSProfileSelector) Metadata(profile string) (map[string]string, error) {
+	contents, err := readContents(s.fs, GetAWSConfigFile(s.home))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseProfileSettings(contents, profile), nil
+}
+
+// GetAWSConfigFile retrieves the path to the AWS config file in the given home directory.
+func GetAWSConfigFile(home string) string {
+	slog.Debug("Read config file", "home", home)
+	return path.Join(home, ".aws", "config")
+}
+
+// readContents reads the contents of a file given its path.
+//
+// This operation can fail if reading the file fails or if the file does not exist.
+func readContents(fs afero.Fs, filePath string) (string, error) {
+	// sanitize the file path
+	filePath = filepath.Clean(filePath)
+
+	file, err := fs.Open(filePath)
+	if err != nil {
+		slog.Debug("Error opening file", "path", filePath, "err", err)
+		return "", err
+	}
+	defer file.Close()
+
+	buf, err := io.ReadAll(file)
+	if err != nil {
+		slog.Debug("Error reading file", "path", filePath, "err", err)
+		return "", err
+	}
+
+	return string(buf), nil
+}
+
+// parseProfiles extracts AWS profile names from the AWS config file contents.
+//
+// In case of no matches, it just returns an empty list and not throws an error.
+func parseProfiles(contents string) []string {
+	pattern := regexp.MustCompile(`\[(?:profile)?(.*?)\]`)
+	matches := pattern.FindAllStringSubmatch(contents, -1)
+
+	if matches == nil {
+		return []string{}
+	}
+
+	var result []string
+	for _, match := range matches {
+		matched := strings.TrimSpace(match[1])
+		result = append(result, matched)
+	}
+
+	return result
+}
+
+// parseProfileSettings extracts the settings of a profile from the AWS config file contents.
+//
+// In case the profile is not found, it returns nil.
+func parseProfileSettings(contents string, profile string) map[string]string {
+	var settings map[string]string
+	inProfile := false
+
+	for _, line := range strings.Split(contents, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.TrimSpace(strings.TrimPrefix(strings.Trim(line, "[]"), "profile"))
+			inProfile = name == profile
+			if inProfile && settings == nil {
+				settings = make(map[string]string)
+			}
+			continue
+		}
+
+		if key, value, ok := strings.Cut(line, "="); ok && inProfile {
+			settings[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	return settings
+}
+
+// getAWSProfiles retrieves a list of AWS profile names from the user's AWS config file.
+//
+// If it fails to read the config file, it returns an empty list and an error.
+func getAWSProfiles(fs afero.Fs, home string) ([]string, error) {
+	configPath := GetAWSConfigFile(home)
+
+	contents, err := readContents(fs, configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseProfiles(contents), nil
+}
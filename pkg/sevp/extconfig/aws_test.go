@@ -0,0 +1,118 @@
+package extconfig
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+// Getting the AWS config file path should return the expected path
+func TestGetConfigFile(t *testing.T) {
+	tempDir := t.TempDir()
+
+	configPath := GetAWSConfigFile(tempDir)
+
+	expectedPath := path.Join(tempDir, ".aws", "config")
+	assert.Equal(t, expectedPath, configPath, "config path should match expected path")
+}
+
+// Reading the contents of a file should return the expected content without error
+func TestReadContents(t *testing.T) {
+	tempDir := t.TempDir()
+	filePath := path.Join(tempDir, "testfile.txt")
+
+	content := "test content"
+	err := os.WriteFile(filePath, []byte(content), 0644)
+	assert.NoError(t, err, "failed to create test file")
+
+	result, err := readContents(afero.NewOsFs(), filePath)
+	assert.NoError(t, err, "expected no error reading file contents")
+	assert.Equal(t, content, result, "file content should match")
+}
+
+// Parsing the AWS config file should return the expected profiles
+func TestGetProfiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		expected []string
+	}{
+		{
+			name: "Single profile",
+			contents: `
+[default]
+[profile my-profile]
+`,
+			expected: []string{"default", "my-profile"},
+		},
+		{
+			name: "Multiple profiles",
+			contents: `
+[profile test1]
+[profile test2]
+[profile test3]
+`,
+			expected: []string{"test1", "test2", "test3"},
+		},
+		{
+			name:     "No profiles",
+			contents: "",
+			expected: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := parseProfiles(test.contents)
+			assert.Equal(t, test.expected, result, "profile list should match expected")
+		})
+	}
+}
+
+// Reading the AWS profile selector should return the profiles of the AWS config in the home directory
+func TestAWSProfileSelectorRead(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	err := afero.WriteFile(fs, "/home/test/.aws/config", []byte("[default]\n[profile dev]\n[profile prod]\n"), 0600)
+	assert.NoError(t, err, "failed to create aws config file")
+
+	targetVar, profiles, err := NewAWSProfileSelector(fs, "/home/test").Read()
+	assert.NoError(t, err, "expected no error reading profiles")
+	assert.Equal(t, "AWS_PROFILE", targetVar)
+	assert.Equal(t, []string{"default", "dev", "prod"}, profiles)
+
+	_, _, err = NewAWSProfileSelector(fs, "/home/other").Read()
+	assert.Error(t, err, "expected error when the aws config file is missing")
+}
+
+// Reading the metadata of an AWS profile should return its settings
+func TestAWSProfileSelectorMetadata(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	contents := `
+[default]
+region = us-east-1
+
+# the dev account
+[profile dev]
+region = eu-west-1
+sso_account_id = 123456789012
+`
+	err := afero.WriteFile(fs, "/home/test/.aws/config", []byte(contents), 0600)
+	assert.NoError(t, err, "failed to create aws config file")
+
+	selector := NewAWSProfileSelector(fs, "/home/test")
+
+	metadata, err := selector.Metadata("dev")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"region": "eu-west-1", "sso_account_id": "123456789012"}, metadata)
+
+	metadata, err = selector.Metadata("default")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"region": "us-east-1"}, metadata)
+
+	metadata, err = selector.Metadata("missing")
+	assert.NoError(t, err)
+	assert.Nil(t, metadata)
+}
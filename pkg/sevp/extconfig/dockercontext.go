@@ -0,0 +1,115 @@
+package extconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+)
+
+type DockerContextSelector struct {
+	fs   afero.Fs
+	home string
+}
+
+func (s *DockerContextSelector) Read() (string, []string, error) {
+	targetVar := "DOCKER_CONTEXT"
+	contexts, err := getDockerContextsFromMeta(s.fs, s.home)
+	return targetVar, contexts, err
+}
+
+func NewDockerContextSelector(fs afero.Fs, home string) *DockerContextSelector {
+	return &DockerContextSelector{fs: fs, home: home}
+}
+
+type dockerContextMeta struct {
+	Name     string `json:"Name"`
+	Metadata struct {
+		Description string `json:"Description"`
+	} `json:"Metadata"`
+	Endpoints map[string]struct {
+		Host string `json:"Host"`
+	} `json:"Endpoints"`
+}
+
+// Metadata returns the docker endpoint and description of the docker context.
+func (s *DockerContextSelector) Metadata(name string) (map[string]string, error) {
+	metas, err := readDockerContextMetas(s.fs, filepath.Join(s.home, ".docker", "contexts", "meta"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, meta := range metas {
+		if meta.Name != name {
+			continue
+		}
+
+		metadata := make(map[string]string)
+		if endpoint, ok := meta.Endpoints["docker"]; ok && endpoint.Host != "" {
+			metadata["endpoint"] = endpoint.Host
+		}
+		if meta.Metadata.Description != "" {
+			metadata["description"] = meta.Metadata.Description
+		}
+		return metadata, nil
+	}
+
+	return nil, nil
+}
+
+// getDockerContextsFromMeta returns the names of all docker contexts in the meta dir
+func getDockerContextsFromMeta(fs afero.Fs, home string) ([]string, error) {
+	return parseDockerContexts(fs, filepath.Join(home, ".docker", "contexts", "meta"))
+}
+
+// parseDockerContexts returns the names of all docker contexts in the meta dir
+func parseDockerContexts(fs afero.Fs, metaDir string) ([]string, error) {
+	metas, err := readDockerContextMetas(fs, metaDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, meta := range metas {
+		names = append(names, meta.Name)
+	}
+
+	if len(names) == 0 {
+		return nil, errors.New("no docker contexts found")
+	}
+
+	return names, nil
+}
+
+// readDockerContextMetas reads the metadata of all named docker contexts in the meta dir, skipping invalid ones
+func readDockerContextMetas(fs afero.Fs, metaDir string) ([]dockerContextMeta, error) {
+	entries, err := afero.ReadDir(fs, metaDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var metas []dockerContextMeta
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		metaPath := filepath.Join(metaDir, entry.Name(), "meta.json")
+		data, err := afero.ReadFile(fs, filepath.Clean(metaPath))
+		if err != nil {
+			continue
+		}
+
+		var meta dockerContextMeta
+		if err := json.Unmarshal(data, &meta); err != nil {
+			continue
+		}
+
+		if meta.Name != "" {
+			metas = append(metas, meta)
+		}
+	}
+
+	return metas, nil
+}
@@ -0,0 +1,74 @@
+package extconfig
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseDockerContexts should return the names of all docker contexts in the meta dir
+func TestParseDockerContexts(t *testing.T) {
+	tmp := t.TempDir()
+
+	// Simulate meta dir context structure
+	ctx1 := filepath.Join(tmp, "ctx-1")
+	ctx2 := filepath.Join(tmp, "ctx-2")
+	_ = os.MkdirAll(ctx1, 0750)
+	_ = os.MkdirAll(ctx2, 0750)
+
+	// Create meta.json files in the meta dir
+	writeMeta := func(dir, name string) {
+		meta := map[string]string{"Name": name}
+		b, _ := json.Marshal(meta)
+		_ = os.WriteFile(filepath.Join(dir, "meta.json"), b, 0600)
+	}
+	writeMeta(ctx1, "default")
+	writeMeta(ctx2, "custom")
+
+	contexts, err := parseDockerContexts(afero.NewOsFs(), tmp)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"default", "custom"}, contexts)
+}
+
+// TestParseDockerContextsEmpty should return an error if the meta dir is empty
+func TestParseDockerContextsEmpty(t *testing.T) {
+	tmp := t.TempDir()
+	_, err := parseDockerContexts(afero.NewOsFs(), tmp)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no docker contexts")
+}
+
+// TestDockerContextSelectorRead should return the contexts of the docker meta dir in the home directory
+func TestDockerContextSelectorRead(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	metaDir := "/home/test/.docker/contexts/meta"
+	require.NoError(t, afero.WriteFile(fs, filepath.Join(metaDir, "abc", "meta.json"), []byte(`{"Name":"remote"}`), 0600))
+	require.NoError(t, afero.WriteFile(fs, filepath.Join(metaDir, "def", "meta.json"), []byte(`not json`), 0600))
+
+	targetVar, contexts, err := NewDockerContextSelector(fs, "/home/test").Read()
+	require.NoError(t, err)
+	require.Equal(t, "DOCKER_CONTEXT", targetVar)
+	require.Equal(t, []string{"remote"}, contexts)
+}
+
+// TestDockerContextSelectorMetadata should return the endpoint and description of the docker context
+func TestDockerContextSelectorMetadata(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	metaDir := "/home/test/.docker/contexts/meta"
+	meta := `{"Name":"remote","Metadata":{"Description":"build host"},"Endpoints":{"docker":{"Host":"ssh://build@example.com"}}}`
+	require.NoError(t, afero.WriteFile(fs, filepath.Join(metaDir, "abc", "meta.json"), []byte(meta), 0600))
+
+	selector := NewDockerContextSelector(fs, "/home/test")
+
+	metadata, err := selector.Metadata("remote")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"endpoint": "ssh://build@example.com", "description": "build host"}, metadata)
+
+	metadata, err = selector.Metadata("missing")
+	require.NoError(t, err)
+	require.Nil(t, metadata)
+}
@@ -2,33 +2,33 @@ package extconfig
 
 import (
 	"errors"
-	"os"
 	"path/filepath"
 	"regexp"
+
+	"github.com/spf13/afero"
 )
 
-type GoEnvSelector struct{}
+type GoEnvSelector struct {
+	fs   afero.Fs
+	home string
+}
 
 func (s GoEnvSelector) Read() (string, []string, error) {
 	targetVar := "GOENV_VERSION"
-	versions, err := getAvailableGoEnvVersions()
+	versions, err := getAvailableGoEnvVersions(s.fs, s.home)
 	return targetVar, versions, err
 }
 
-func NewGoEnvSelector() *GoEnvSelector {
-	return &GoEnvSelector{}
+func NewGoEnvSelector(fs afero.Fs, home string) *GoEnvSelector {
+	return &GoEnvSelector{fs: fs, home: home}
 }
 
-func getAvailableGoEnvVersions() ([]string, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return nil, err
-	}
-	return readGoEnvVersions(filepath.Join(home, ".goenv", "versions"))
+func getAvailableGoEnvVersions(fs afero.Fs, home string) ([]string, error) {
+	return readGoEnvVersions(fs, filepath.Join(home, ".goenv", "versions"))
 }
 
-func readGoEnvVersions(tfEnvPath string) ([]string, error) {
-	entries, err := os.ReadDir(tfEnvPath)
+func readGoEnvVersions(fs afero.Fs, tfEnvPath string) ([]string, error) {
+	entries, err := afero.ReadDir(fs, tfEnvPath)
 	if err != nil {
 		return nil, err
 	}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/require"
 )
 
@@ -18,7 +19,7 @@ func TestReadGoEnvVersions(t *testing.T) {
 	_ = os.MkdirAll(version1, 0750)
 	_ = os.MkdirAll(version2, 0750)
 
-	versions, err := readGoEnvVersions(tmp)
+	versions, err := readGoEnvVersions(afero.NewOsFs(), tmp)
 
 	require.NoError(t, err)
 	require.ElementsMatch(t, []string{"1.18.0", "1.19.1"}, versions)
@@ -38,7 +39,7 @@ func TestReadGoEnvVersionsInvalidVersion(t *testing.T) {
 	_ = os.MkdirAll(version3, 0750)
 	_ = os.MkdirAll(version4, 0750)
 
-	versions, err := readGoEnvVersions(tmp)
+	versions, err := readGoEnvVersions(afero.NewOsFs(), tmp)
 
 	require.NoError(t, err)
 
@@ -49,7 +50,19 @@ func TestReadGoEnvVersionsInvalidVersion(t *testing.T) {
 // TestReadGoEnvVersionsEmpty should return an error if the goenv dir is empty
 func TestReadGoEnvVersionsEmpty(t *testing.T) {
 	tmp := t.TempDir()
-	_, err := readGoEnvVersions(tmp)
+	_, err := readGoEnvVersions(afero.NewOsFs(), tmp)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "no goenv versions")
 }
+
+// TestGoEnvSelectorRead should return the versions in the home directory
+func TestGoEnvSelectorRead(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, fs.MkdirAll("/home/test/.goenv/versions/1.22.1", 0750))
+	require.NoError(t, fs.MkdirAll("/home/test/.goenv/versions/latest", 0750))
+
+	targetVar, versions, err := NewGoEnvSelector(fs, "/home/test").Read()
+	require.NoError(t, err)
+	require.Equal(t, "GOENV_VERSION", targetVar)
+	require.Equal(t, []string{"1.22.1"}, versions)
+}
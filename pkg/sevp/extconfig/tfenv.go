@@ -2,35 +2,35 @@ package extconfig
 
 import (
 	"errors"
-	"os"
 	"path/filepath"
 	"regexp"
+
+	"github.com/spf13/afero"
 )
 
-type TfEnvSelector struct{}
+type TfEnvSelector struct {
+	fs   afero.Fs
+	home string
+}
 
 func (s TfEnvSelector) Read() (string, []string, error) {
 	targetVar := "TFENV_TERRAFORM_VERSION"
-	versions, err := getAvailableTfenvVersions()
+	versions, err := getAvailableTfenvVersions(s.fs, s.home)
 	return targetVar, versions, err
 }
 
-func NewTfEnvSelector() *TfEnvSelector {
-	return &TfEnvSelector{}
+func NewTfEnvSelector(fs afero.Fs, home string) *TfEnvSelector {
+	return &TfEnvSelector{fs: fs, home: home}
 }
 
 // getAvailableTfenvVersions returns all available versions of Terraform managed by tfenv
-func getAvailableTfenvVersions() ([]string, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return nil, err
-	}
-	return readTfenvVersions(filepath.Join(home, ".tfenv", "versions"))
+func getAvailableTfenvVersions(fs afero.Fs, home string) ([]string, error) {
+	return readTfenvVersions(fs, filepath.Join(home, ".tfenv", "versions"))
 }
 
 // readTfEnvVersions returns all available versions of Terraform managed by tfenv
-func readTfenvVersions(tfEnvPath string) ([]string, error) {
-	entries, err := os.ReadDir(tfEnvPath)
+func readTfenvVersions(fs afero.Fs, tfEnvPath string) ([]string, error) {
+	entries, err := afero.ReadDir(fs, tfEnvPath)
 	if err != nil {
 		return nil, err
 	}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/require"
 )
 
@@ -18,7 +19,7 @@ func TestReadTfenvVersions(t *testing.T) {
 	_ = os.MkdirAll(version1, 0750)
 	_ = os.MkdirAll(version2, 0750)
 
-	v, err := readTfenvVersions(tmp)
+	v, err := readTfenvVersions(afero.NewOsFs(), tmp)
 
 	require.NoError(t, err)
 	require.ElementsMatch(t, []string{"0.1.0", "11.2.0"}, v)
@@ -38,7 +39,7 @@ func TestReadTfenvVersionsInvalidVersion(t *testing.T) {
 	_ = os.MkdirAll(version3, 0750)
 	_ = os.MkdirAll(version4, 0750)
 
-	v, err := readTfenvVersions(tmp)
+	v, err := readTfenvVersions(afero.NewOsFs(), tmp)
 
 	require.NoError(t, err)
 
@@ -49,7 +50,19 @@ func TestReadTfenvVersionsInvalidVersion(t *testing.T) {
 // TestReadTfenvVersionsEmpty should return an error if the tfenv dir is empty
 func TestReadTfenvVersionsEmpty(t *testing.T) {
 	tmp := t.TempDir()
-	_, err := readTfenvVersions(tmp)
+	_, err := readTfenvVersions(afero.NewOsFs(), tmp)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "no tfenv versions")
 }
+
+// TestTfEnvSelectorRead should return the versions in the home directory
+func TestTfEnvSelectorRead(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, fs.MkdirAll("/home/test/.tfenv/versions/1.5.7", 0750))
+	require.NoError(t, fs.MkdirAll("/home/test/.tfenv/versions/latest", 0750))
+
+	targetVar, versions, err := NewTfEnvSelector(fs, "/home/test").Read()
+	require.NoError(t, err)
+	require.Equal(t, "TFENV_TERRAFORM_VERSION", targetVar)
+	require.Equal(t, []string{"1.5.7"}, versions)
+}
@@ -0,0 +1,179 @@
+package sevp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the file format of a config file.
+//
+// All formats have identical semantics: a YAML or JSON config holds the same keys and tables as a TOML config.
+type Format string
+
+const (
+	FormatTOML Format = "toml"
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+)
+
+// configFileExtensions are the extensions of config files, in the order of precedence
+// when files with several of them exist next to each other.
+var configFileExtensions = []string{".toml", ".yaml", ".yml", ".json"}
+
+// FormatOf returns the format of the config file at the path by its extension.
+// Files with unknown extensions are TOML.
+func FormatOf(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".json":
+		return FormatJSON
+	default:
+		return FormatTOML
+	}
+}
+
+// ParseFormat parses the name of a config file format.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "toml":
+		return FormatTOML, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	case "json":
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unknown config format %q, supported formats are toml, yaml and json", name)
+	}
+}
+
+// findConfigFile returns the first existing file of the path with any of the config file extensions,
+// in the order of precedence: TOML, YAML and JSON. For example, sevp.yaml is found for sevp.toml.
+//
+// The returned bool is false if none of them exists.
+func findConfigFile(fs afero.Fs, path string) (string, bool, error) {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+
+	var found []string
+	for _, ext := range configFileExtensions {
+		exists, err := afero.Exists(fs, base+ext)
+		if err != nil {
+			return "", false, err
+		}
+		if exists {
+			found = append(found, base+ext)
+		}
+	}
+
+	if len(found) == 0 {
+		return path, false, nil
+	}
+
+	if len(found) > 1 {
+		slog.Warn("Several config files found, using the first one", "using", found[0], "ignoring", strings.Join(found[1:], ", "))
+	}
+
+	return found[0], true, nil
+}
+
+// readSettings reads the raw settings of a config document in the format.
+func readSettings(data []byte, format Format) (*viper.Viper, error) {
+	v := viper.New()
+	v.SetConfigType(string(format))
+
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// ConvertConfig converts a config document from one format into another.
+//
+// The document must be a readable config. Comments are not carried over, and keys are sorted
+// and lowercased, just like they are when the config is read.
+func ConvertConfig(data []byte, from Format, to Format) ([]byte, error) {
+	if _, err := ReadConfigFormat(bytes.NewReader(data), from); err != nil {
+		return nil, err
+	}
+
+	v, err := readSettings(data, from)
+	if err != nil {
+		return nil, err
+	}
+	settings := v.AllSettings()
+
+	switch to {
+	case FormatTOML:
+		return toml.Marshal(settings)
+	case FormatYAML:
+		var b bytes.Buffer
+		encoder := yaml.NewEncoder(&b)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(settings); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	case FormatJSON:
+		out, err := json.MarshalIndent(settings, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(out, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unknown config format %q", to)
+	}
+}
+
+// keyLines maps the dotted, lowercased keys of a config document in the format to the lines they are defined on.
+func keyLines(data []byte, format Format) map[string]int {
+	if format == FormatTOML {
+		return tomlKeyLines(data)
+	}
+
+	// JSON is a subset of YAML, so both are mapped the same way
+	return yamlKeyLines(data)
+}
+
+// yamlKeyLines maps the dotted, lowercased keys of a YAML document to the lines they are defined on.
+//
+// Parse errors are ignored, as the document is parsed by viper beforehand.
+func yamlKeyLines(data []byte) map[string]int {
+	lines := make(map[string]int)
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return lines
+	}
+
+	var walk func(node *yaml.Node, path string)
+	walk = func(node *yaml.Node, path string) {
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+
+			keyPath := strings.ToLower(key.Value)
+			if path != "" {
+				keyPath = path + "." + keyPath
+			}
+
+			lines[keyPath] = key.Line
+			walk(value, keyPath)
+		}
+	}
+	walk(doc.Content[0], "")
+
+	return lines
+}
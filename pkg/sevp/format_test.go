@@ -0,0 +1,163 @@
+package sevp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const formatTestTOML = `default = "kube"
+
+[kube]
+target_var = "KUBECONFIG"
+possible_values = ["dev", "prod"]
+merge = "append"
+
+[kube.meta.dev]
+cluster = "eks-dev"
+`
+
+const formatTestYAML = `default: kube
+kube:
+  target_var: KUBECONFIG
+  possible_values:
+    - dev
+    - prod
+  merge: append
+  meta:
+    dev:
+      cluster: eks-dev
+`
+
+const formatTestJSON = `{
+  "default": "kube",
+  "kube": {
+    "target_var": "KUBECONFIG",
+    "possible_values": ["dev", "prod"],
+    "merge": "append",
+    "meta": {"dev": {"cluster": "eks-dev"}}
+  }
+}
+`
+
+// The format of a config file should be detected by its extension
+func TestFormatOf(t *testing.T) {
+	assert.Equal(t, FormatTOML, FormatOf("/a/sevp.toml"))
+	assert.Equal(t, FormatTOML, FormatOf("/a/sevp"))
+	assert.Equal(t, FormatYAML, FormatOf("/a/sevp.yaml"))
+	assert.Equal(t, FormatYAML, FormatOf("/a/.sevp.YML"))
+	assert.Equal(t, FormatJSON, FormatOf("/a/sevp.json"))
+
+	format, err := ParseFormat("yml")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatYAML, format)
+
+	_, err = ParseFormat("ini")
+	assert.ErrorContains(t, err, `unknown config format "ini"`)
+}
+
+// Configs in all formats should have identical semantics
+func TestReadConfigFormats(t *testing.T) {
+	for format, content := range map[Format]string{
+		FormatTOML: formatTestTOML,
+		FormatYAML: formatTestYAML,
+		FormatJSON: formatTestJSON,
+	} {
+		t.Run(string(format), func(t *testing.T) {
+			cfg, err := ReadConfigFormat(bytes.NewReader([]byte(content)), format)
+			require.NoError(t, err)
+			assert.Equal(t, "kube", cfg.Default)
+			assert.Equal(t, MergeAppend, cfg.sections["kube"].merge)
+
+			s, err := cfg.FromConfig("kube")
+			require.NoError(t, err)
+			assert.Equal(t, "KUBECONFIG", s.TargetVar)
+			assert.Equal(t, []string{"dev", "prod"}, s.PossibleValues)
+			assert.Equal(t, map[string]map[string]string{"dev": {"cluster": "eks-dev"}}, s.Meta)
+		})
+	}
+}
+
+// Diagnostics of YAML and JSON configs should point to the lines of the keys
+func TestValidateFormats(t *testing.T) {
+	root := newTestRoot()
+	writeTestFiles(t, root, map[string]string{
+		"/home/test/sevp.yaml": "kube:\n  target_var: \"1X\"\n  possible_values: [a]\n",
+		"/home/test/sevp.json": "{\n  \"kube\": {\n    \"target_var\": \"X\",\n    \"possible_values\": [\"a\", \"a\"]\n  }\n}\n",
+	})
+
+	cfg, err := LoadConfig(root, "/home/test/sevp.yaml")
+	require.NoError(t, err)
+	require.Len(t, cfg.Validate(), 1)
+	assert.Equal(t, `/home/test/sevp.yaml:2: kube.target_var: "1X" is not a valid environment variable name`, cfg.Validate()[0].String())
+
+	cfg, err = LoadConfig(root, "/home/test/sevp.json")
+	require.NoError(t, err)
+	require.Len(t, cfg.Validate(), 1)
+	assert.Equal(t, `/home/test/sevp.json:4: kube.possible_values: contains "a" more than once`, cfg.Validate()[0].String())
+}
+
+// Config files in any format should be found, in the order of precedence TOML, YAML and JSON
+func TestConfigPathFormats(t *testing.T) {
+	t.Setenv("SEVP_CONFIG", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	root := newTestRoot()
+
+	writeTestFiles(t, root, map[string]string{"/home/test/sevp.toml": "", "/home/test/.config/sevp.json": ""})
+	assert.Equal(t, "/home/test/.config/sevp.json", ConfigPath(root, ""), "the directory takes precedence over the format")
+
+	writeTestFiles(t, root, map[string]string{"/home/test/.config/sevp.yml": ""})
+	assert.Equal(t, "/home/test/.config/sevp.yml", ConfigPath(root, ""))
+
+	writeTestFiles(t, root, map[string]string{"/home/test/.config/sevp.toml": ""})
+	assert.Equal(t, "/home/test/.config/sevp.toml", ConfigPath(root, ""))
+}
+
+// Project configs and includes should be read in any format
+func TestInitConfigFormats(t *testing.T) {
+	root := newTestRoot()
+	root.Workdir = "/home/test/repo"
+	t.Setenv("SEVP_SYSTEM_CONFIG", "")
+	t.Setenv("SEVP_TEAM_CONFIG", "")
+
+	writeTestFiles(t, root, map[string]string{
+		"/home/test/.config/sevp.toml":   "include = [\"shared.json\"]\n",
+		"/home/test/.config/shared.json": formatTestJSON,
+		"/home/test/repo/.git/HEAD":      "",
+		"/home/test/repo/.sevp.yaml":     "kube:\n  merge: append\n  possible_values: [staging]\n",
+	})
+
+	cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	require.NoError(t, err)
+
+	s, err := cfg.FromConfig("kube")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dev", "prod", "staging"}, s.PossibleValues)
+	assert.Equal(t, "/home/test/repo/.sevp.yaml", cfg.Files[len(cfg.Files)-1].Path)
+}
+
+// Converting a config should keep its semantics in every format
+func TestConvertConfig(t *testing.T) {
+	for _, from := range []Format{FormatTOML, FormatYAML, FormatJSON} {
+		for _, to := range []Format{FormatTOML, FormatYAML, FormatJSON} {
+			t.Run(string(from)+" to "+string(to), func(t *testing.T) {
+				source := map[Format]string{FormatTOML: formatTestTOML, FormatYAML: formatTestYAML, FormatJSON: formatTestJSON}[from]
+
+				converted, err := ConvertConfig([]byte(source), from, to)
+				require.NoError(t, err)
+
+				cfg, err := ReadConfigFormat(bytes.NewReader(converted), to)
+				require.NoError(t, err)
+				s, err := cfg.FromConfig("kube")
+				require.NoError(t, err)
+				assert.Equal(t, []string{"dev", "prod"}, s.PossibleValues)
+				assert.Equal(t, MergeAppend, cfg.sections["kube"].merge)
+			})
+		}
+	}
+
+	_, err := ConvertConfig([]byte("[broken"), FormatTOML, FormatYAML)
+	assert.Error(t, err)
+}
@@ -0,0 +1,258 @@
+package sevp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// HistoryEntry is a change of an environment variable in the state, recorded in its history, see ReadHistory.
+//
+// All changes of a single update, e.g. of all variables of a set, are recorded at the same time.
+type HistoryEntry struct {
+	// Time is when the variable was changed.
+	Time time.Time `json:"time"`
+
+	// Var is the changed environment variable.
+	Var string `json:"var"`
+
+	// Selector is the selector the new value was picked from, if known.
+	Selector string `json:"selector,omitempty"`
+
+	// Old is the value before the change, or nil if the variable was not set.
+	Old *string `json:"old"`
+
+	// OldSelector is the selector the old value was picked from, if known.
+	OldSelector string `json:"old_selector,omitempty"`
+
+	// OldItem is the item the old value was rendered from, if known, see StateEntry.Item.
+	OldItem string `json:"old_item,omitempty"`
+
+	// OldVerbatim reports whether the old value was exported unquoted, see StateEntry.Verbatim.
+	OldVerbatim bool `json:"old_verbatim,omitempty"`
+
+	// New is the value after the change, or nil if the variable was unset.
+	New *string `json:"new"`
+
+	// Source describes how the variable was changed, see StateEntry.
+	Source string `json:"source"`
+
+	// Undoes is the time of the change reverted by this one, if it was reverted by Undo.
+	Undoes *time.Time `json:"undoes,omitempty"`
+}
+
+// oldSelector returns the selector the old value was picked from. Entries of earlier versions of sevp
+// don't record it, the selector of the new value is the best guess then.
+func (e HistoryEntry) oldSelector() string {
+	if e.OldSelector != "" {
+		return e.OldSelector
+	}
+
+	return e.Selector
+}
+
+// HistoryFilter selects history entries. Empty fields match all entries.
+type HistoryFilter struct {
+	// Selector matches entries of the selector.
+	Selector string
+
+	// Var matches entries of the environment variable.
+	Var string
+
+	// Source matches entries of the source, see StateEntry.
+	Source string
+
+	// Since matches entries at or after the time.
+	Since time.Time
+}
+
+// Match reports whether the entry is selected by the filter.
+func (f HistoryFilter) Match(entry HistoryEntry) bool {
+	return (f.Selector == "" || entry.Selector == f.Selector) &&
+		(f.Var == "" || entry.Var == f.Var) &&
+		(f.Source == "" || entry.Source == f.Source) &&
+		!entry.Time.Before(f.Since)
+}
+
+// HistoryFile returns the path of the history of the state file of the root.
+//
+// The history is an append-only log with a JSON encoded HistoryEntry per line.
+func HistoryFile(root *Root) string {
+	return sidecarPath(root) + ".history"
+}
+
+// ReadHistory returns the history of the state of the root, from the oldest to the newest change.
+//
+// A missing history is not an error and results in no entries. Lines that can't be parsed are skipped.
+func ReadHistory(root *Root) ([]HistoryEntry, error) {
+	path := HistoryFile(root)
+
+	f, err := root.Fs.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var history []HistoryEntry
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			slog.Warn("Skipping invalid history entry", "path", path, "line", line, "err", err)
+			continue
+		}
+		history = append(history, entry)
+	}
+
+	return history, scanner.Err()
+}
+
+// appendHistory appends the changes of an update at the time to the history of the root.
+func appendHistory(root *Root, at time.Time, undoes time.Time, changes []HistoryEntry) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	f, err := root.Fs.OpenFile(HistoryFile(root), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+
+	writer := bufio.NewWriter(f)
+	for _, change := range changes {
+		change.Time = at
+		if !undoes.IsZero() {
+			change.Undoes = &undoes
+		}
+
+		line, err := json.Marshal(change)
+		if err != nil {
+			return err
+		}
+		if _, err := writer.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
+
+// RevertCheck checks the changes before they are reverted, e.g. to confirm restoring dangerous values.
+// An error stops reverting the changes.
+type RevertCheck func(changes []HistoryEntry) error
+
+// Undo reverts the latest change of the state of the root that was not reverted yet, and returns its entries.
+// The changes are checked before they are reverted, unless check is nil.
+//
+// Changes are reverted through the state like any other change, so they are recorded in the history as SourceUndo.
+// Undoing repeatedly walks back through the history.
+func Undo(root *Root, check RevertCheck) ([]HistoryEntry, error) {
+	unlock, err := lockFile(root.Fs, sidecarPath(root))
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	history, err := ReadHistory(root)
+	if err != nil {
+		return nil, err
+	}
+
+	undone := make(map[time.Time]bool)
+	for end := len(history); end > 0; {
+		// the changes of an update share the time they were made at
+		start := end - 1
+		for start > 0 && history[start-1].Time.Equal(history[end-1].Time) {
+			start--
+		}
+		change := history[start:end]
+		end = start
+
+		if change[0].Undoes != nil {
+			undone[change[0].Undoes.UTC()] = true
+			continue
+		}
+		if undone[change[0].Time.UTC()] {
+			continue
+		}
+
+		if check != nil {
+			if err := check(change); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := revert(root, change, SourceUndo, change[0].Time); err != nil {
+			return nil, err
+		}
+		return change, nil
+	}
+
+	return nil, errors.New("nothing to undo")
+}
+
+// Back restores the value the selector had before its latest change, and returns the reverted entry.
+// The change is checked before it is reverted, unless check is nil.
+//
+// Going back twice returns to the value before going back, just like cd -.
+func Back(root *Root, selector string, check RevertCheck) (HistoryEntry, error) {
+	unlock, err := lockFile(root.Fs, sidecarPath(root))
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+	defer unlock()
+
+	history, err := ReadHistory(root)
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Selector != selector {
+			continue
+		}
+
+		if check != nil {
+			if err := check(history[i : i+1]); err != nil {
+				return HistoryEntry{}, err
+			}
+		}
+
+		if err := revert(root, history[i:i+1], SourceBack, time.Time{}); err != nil {
+			return HistoryEntry{}, err
+		}
+		return history[i], nil
+	}
+
+	return HistoryEntry{}, fmt.Errorf("no history for selector %s", selector)
+}
+
+// revert restores the old values of the changes. The state file must be locked, see updateState.
+func revert(root *Root, changes []HistoryEntry, source string, undoes time.Time) error {
+	update := stateUpdate{source: source, undoes: undoes}
+
+	for _, change := range changes {
+		if change.Old == nil {
+			update.unset = append(update.unset, change.Var)
+			continue
+		}
+		update.entries = append(update.entries, StateEntry{
+			Var:      change.Var,
+			Value:    *change.Old,
+			Selector: change.oldSelector(),
+			Item:     change.OldItem,
+			Source:   source,
+			Verbatim: change.OldVerbatim,
+		})
+	}
+
+	return rewriteState(root, update)
+}
@@ -0,0 +1,159 @@
+package sevp
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useTestClock makes every state update happen a minute after the previous one
+func useTestClock(t *testing.T) {
+	t.Helper()
+
+	original := now
+	t.Cleanup(func() { now = original })
+
+	at := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time {
+		at = at.Add(time.Minute)
+		return at
+	}
+}
+
+// Every change of the state should be recorded with its old and new value
+func TestReadHistory(t *testing.T) {
+	useTestClock(t)
+	root := newTestRoot()
+
+	history, err := ReadHistory(root)
+	require.NoError(t, err)
+	assert.Empty(t, history, "there is no history without changes")
+
+	require.NoError(t, WriteAssignments(root, SourcePicker, []Assignment{{Selector: "aws", TargetVar: "AWS_PROFILE", Item: "dev", Value: "dev"}}))
+	require.NoError(t, WriteAssignments(root, SourcePicker, []Assignment{{Selector: "aws", TargetVar: "AWS_PROFILE", Item: "dev", Value: "dev"}}))
+	require.NoError(t, WriteAssignments(root, "set prod", []Assignment{
+		{Selector: "aws", TargetVar: "AWS_PROFILE", Item: "prod", Value: "prod"},
+		{Selector: "kube", TargetVar: "KUBECONFIG", Item: "prod", Value: "/kube/prod.yaml"},
+	}))
+	require.NoError(t, UnsetValues(root, []string{"KUBECONFIG"}))
+
+	history, err = ReadHistory(root)
+	require.NoError(t, err)
+	require.Len(t, history, 4, "writing an unchanged value is not a change")
+
+	assert.Equal(t, "AWS_PROFILE", history[0].Var)
+	assert.Nil(t, history[0].Old)
+	assert.Equal(t, "dev", *history[0].New)
+
+	assert.Equal(t, "dev", *history[1].Old)
+	assert.Equal(t, "prod", *history[1].New)
+	assert.Equal(t, history[1].Time, history[2].Time, "changes of an update share their time")
+
+	assert.Equal(t, "/kube/prod.yaml", *history[3].Old)
+	assert.Nil(t, history[3].New)
+	assert.Equal(t, SourceLibrary, history[3].Source)
+
+	var filtered []HistoryEntry
+	for _, entry := range history {
+		if (HistoryFilter{Selector: "aws", Since: history[1].Time}).Match(entry) {
+			filtered = append(filtered, entry)
+		}
+	}
+	assert.Equal(t, []HistoryEntry{history[1]}, filtered)
+
+	require.NoError(t, UnsetValuesFrom(root, SourcePicker, []string{"AWS_PROFILE"}))
+
+	history, err = ReadHistory(root)
+	require.NoError(t, err)
+	require.Len(t, history, 5)
+	assert.Nil(t, history[4].New)
+	assert.Equal(t, SourcePicker, history[4].Source)
+}
+
+// Undoing should walk back through the history, one update at a time
+func TestUndo(t *testing.T) {
+	useTestClock(t)
+	root := newTestRoot()
+
+	require.NoError(t, WriteAssignments(root, SourcePicker, []Assignment{{Selector: "aws", TargetVar: "AWS_PROFILE", Item: "dev", Value: "dev"}}))
+	require.NoError(t, WriteAssignments(root, "set prod", []Assignment{
+		{Selector: "aws", TargetVar: "AWS_PROFILE", Item: "prod", Value: "prod"},
+		{Selector: "kube", TargetVar: "KUBECONFIG", Item: "prod", Value: "/kube/prod.yaml"},
+	}))
+
+	reverted, err := Undo(root, nil)
+	require.NoError(t, err)
+	assert.Len(t, reverted, 2, "all changes of the set should be undone")
+	state, err := ReadState(root)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"AWS_PROFILE": "dev"}, state)
+
+	reverted, err = Undo(root, nil)
+	require.NoError(t, err)
+	assert.Len(t, reverted, 1)
+	state, err = ReadState(root)
+	require.NoError(t, err)
+	assert.Empty(t, state)
+
+	_, err = Undo(root, nil)
+	assert.ErrorContains(t, err, "nothing to undo")
+
+	history, err := ReadHistory(root)
+	require.NoError(t, err)
+	assert.Equal(t, SourceUndo, history[len(history)-1].Source, "undoing is recorded as well")
+}
+
+// Going back should toggle between the latest two values of a selector
+func TestBack(t *testing.T) {
+	useTestClock(t)
+	root := newTestRoot()
+
+	for _, profile := range []string{"dev", "staging", "prod"} {
+		require.NoError(t, WriteAssignments(root, SourcePicker, []Assignment{{Selector: "aws", TargetVar: "AWS_PROFILE", Item: profile, Value: profile}}))
+	}
+	require.NoError(t, WriteAssignments(root, SourcePicker, []Assignment{{Selector: "kube", TargetVar: "KUBECONFIG", Item: "dev", Value: "dev"}}))
+
+	for _, want := range []string{"staging", "prod", "staging"} {
+		_, err := Back(root, "aws", nil)
+		require.NoError(t, err)
+
+		state, err := ReadState(root)
+		require.NoError(t, err)
+		assert.Equal(t, want, state["AWS_PROFILE"])
+		assert.Equal(t, "dev", state["KUBECONFIG"], "other selectors are not changed")
+	}
+
+	_, err := Back(root, "gcp", nil)
+	assert.ErrorContains(t, err, "no history for selector gcp")
+}
+
+// Undoing and going back should not revert changes their check refuses
+func TestRevertCheck(t *testing.T) {
+	useTestClock(t)
+	root := newTestRoot()
+
+	for _, profile := range []string{"prod", "dev"} {
+		require.NoError(t, WriteAssignments(root, SourcePicker, []Assignment{{Selector: "aws", TargetVar: "AWS_PROFILE", Item: profile, Value: profile}}))
+	}
+
+	var checked []HistoryEntry
+	refuse := func(changes []HistoryEntry) error {
+		checked = changes
+		return errors.New("refused")
+	}
+
+	_, err := Undo(root, refuse)
+	assert.ErrorContains(t, err, "refused")
+	require.Len(t, checked, 1)
+	assert.Equal(t, "prod", *checked[0].Old)
+
+	_, err = Back(root, "aws", refuse)
+	assert.ErrorContains(t, err, "refused")
+
+	state, err := ReadState(root)
+	require.NoError(t, err)
+	assert.Equal(t, "dev", state["AWS_PROFILE"], "refused changes are not reverted")
+}
@@ -0,0 +1,50 @@
+package sevp
+
+import "fmt"
+
+// SupportedShells lists the shells sevp can render a hook for.
+var SupportedShells = []string{
+	"bash",
+	"zsh",
+}
+
+// The hooks apply the directory values of the working directory whenever it changes, see SwitchDirValues,
+// before evaluating the state file.
+
+const ZshHook string = `function _sevp() {
+    if [[ "$PWD" != "$_SEVP_PWD" ]]; then
+        _SEVP_PWD="$PWD"
+        eval "$(sevp hook-dir)"
+    fi
+    if [[ -f ~/.sevp ]]; then
+        eval "$(cat ~/.sevp)"
+    fi
+}
+precmd_functions+=(_sevp)`
+
+const BashHook string = `function _sevp() {
+    if [[ "$PWD" != "$_SEVP_PWD" ]]; then
+        _SEVP_PWD="$PWD"
+        eval "$(sevp hook-dir)"
+    fi
+    if [[ -f ~/.sevp ]]; then
+        eval "$(cat ~/.sevp)"
+    fi
+}
+PROMPT_COMMAND="_sevp; ${PROMPT_COMMAND}"`
+
+// shellToHook maps the shell name to the corresponding hook.
+var shellToHook = map[string]string{
+	"bash": BashHook,
+	"zsh":  ZshHook,
+}
+
+// Hook returns the shell hook for the specified shell.
+func Hook(shell string) (string, error) {
+	hook, ok := shellToHook[shell]
+	if !ok {
+		return "", fmt.Errorf("unsupported shell: %s", shell)
+	}
+
+	return hook, nil
+}
@@ -0,0 +1,21 @@
+package sevp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Every supported shell should have a hook, unsupported shells should error
+func TestHook(t *testing.T) {
+	for _, shell := range SupportedShells {
+		t.Run(shell, func(t *testing.T) {
+			hook, err := Hook(shell)
+			assert.NoError(t, err, "expected no error getting hook")
+			assert.Contains(t, hook, "_sevp", "hook should define the _sevp function")
+		})
+	}
+
+	_, err := Hook("fish")
+	assert.Error(t, err, "expected error for unsupported shell")
+}
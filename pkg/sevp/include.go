@@ -0,0 +1,154 @@
+package sevp
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// includeLoader loads config files and, recursively, the files they include.
+//
+// Config files can include other files with a top-level include key:
+//
+//	include = ["~/work/platform/sevp.d/*.toml", "shared.toml"]
+//
+// Paths starting with ~ are relative to the home directory, other relative paths are relative to
+// the directory of the including file. Patterns with glob characters may match no files,
+// while plain paths must exist.
+//
+// A file and all files it includes form a single unit within its layer:
+// defining the same selector twice across them is an error.
+type includeLoader struct {
+	root *Root
+
+	// loaded holds the files that were already loaded, so files included twice are only merged once.
+	loaded map[string]bool
+}
+
+// newIncludeLoader creates a new includeLoader for the root.
+func newIncludeLoader(root *Root) *includeLoader {
+	return &includeLoader{root: root, loaded: make(map[string]bool)}
+}
+
+// load reads the config file and merges the files it includes into it.
+//
+// The stack holds the chain of files that included the file, to detect include cycles.
+func (l *includeLoader) load(file ConfigFile, stack []string) (*Config, error) {
+	filePath := filepath.Clean(file.Path)
+
+	for i, including := range stack {
+		if including == filePath {
+			cycle := append(append([]string{}, stack[i:]...), filePath)
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	l.loaded[filePath] = true
+
+	cfg, err := readConfigFile(l.root, file)
+	if err != nil {
+		return nil, err
+	}
+
+	// the chain of files including the included files
+	chain := append(slices.Clone(stack), filePath)
+
+	for _, pattern := range cfg.includes {
+		paths, err := l.resolve(pattern, filepath.Dir(filePath))
+		if err != nil {
+			return nil, fmt.Errorf("%s: include %q: %v", filePath, pattern, err)
+		}
+
+		for _, includePath := range paths {
+			if l.loaded[includePath] && !slices.Contains(chain, includePath) {
+				slog.Debug("Config file already included, skipping", "path", includePath, "included_from", filePath)
+				continue
+			}
+
+			included, err := l.load(ConfigFile{Path: includePath, Layer: file.Layer}, chain)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := cfg.include(included); err != nil {
+				return nil, fmt.Errorf("%s: %w", filePath, err)
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// resolve returns the sorted paths of the files matching the include pattern.
+func (l *includeLoader) resolve(pattern string, dir string) ([]string, error) {
+	if pattern == "~" || strings.HasPrefix(pattern, "~/") {
+		pattern = l.root.HomePath(strings.TrimPrefix(pattern, "~"))
+	} else if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+	pattern = filepath.Clean(pattern)
+
+	if !strings.ContainsAny(pattern, "*?[") {
+		exists, err := afero.Exists(l.root.Fs, pattern)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			// the error intentionally does not wrap os.ErrNotExist,
+			// as a missing include is an error even for optional config files
+			return nil, fmt.Errorf("file %s does not exist", pattern)
+		}
+		return []string{pattern}, nil
+	}
+
+	matches, err := afero.Glob(l.root.Fs, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		slog.Warn("Config include pattern matched no files", "pattern", pattern)
+	}
+	sort.Strings(matches)
+
+	return matches, nil
+}
+
+// include merges the config of an included file into the config.
+//
+// The default selector of the including file takes precedence over the ones of included files.
+func (c *Config) include(other *Config) error {
+	for name, s := range other.sections {
+		if existing, ok := c.sections[name]; ok {
+			return fmt.Errorf("duplicate selector %q in %s and %s", name, sourcePath(existing), sourcePath(s))
+		}
+		c.sections[name] = s
+	}
+
+	if c.Default == "" && other.Default != "" {
+		c.Default = other.Default
+		c.defaultSource = other.defaultSource
+		c.defaultLine = other.defaultLine
+	}
+
+	c.mergeOptions(other.rawOptions, false)
+	c.mergeTheme(other.rawTheme, false)
+	c.mergeKeys(other.rawKeys, false)
+	c.mergeSets(other, false)
+	c.mergeValues(other, false)
+	c.Files = append(c.Files, other.Files...)
+	c.diagnostics = append(c.diagnostics, other.diagnostics...)
+	return nil
+}
+
+// sourcePath returns the path of the file the section was first defined in.
+func sourcePath(s *section) string {
+	if len(s.sources) == 0 {
+		return "<unknown>"
+	}
+
+	return s.sources[0].Path
+}
@@ -0,0 +1,135 @@
+package sevp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Included files should be merged into the including file's layer
+func TestInclude(t *testing.T) {
+	root := newTestRoot()
+	writeTestFiles(t, root, map[string]string{
+		"/home/test/.config/sevp.toml": `
+include = ["~/work/platform/sevp.d/*.toml", "local.toml"]
+
+[personal]
+target_var = "PERSONAL_VAR"
+possible_values = ["a"]
+`,
+		"/home/test/.config/local.toml": `
+default = "local"
+
+[local]
+target_var = "LOCAL_VAR"
+possible_values = ["b"]
+`,
+		"/home/test/work/platform/sevp.d/10-aws.toml": `
+include = ["../common.toml"]
+
+[aws]
+target_var = "AWS_PROFILE"
+possible_values = ["platform-dev"]
+`,
+		"/home/test/work/platform/sevp.d/20-kube.toml": `
+[kube]
+target_var = "KUBECONFIG"
+possible_values = ["dev.yaml"]
+`,
+		"/home/test/work/platform/common.toml": `
+[region]
+target_var = "AWS_REGION"
+possible_values = ["eu-west-1"]
+`,
+	})
+
+	cfg, err := LoadConfig(root, "/home/test/.config/sevp.toml")
+	assert.NoError(t, err, "expected no error loading config with includes")
+
+	assert.Equal(t, []string{"aws", "kube", "local", "personal", "region"}, cfg.Names())
+	assert.Equal(t, "local", cfg.Default, "default of included files should be used if the including file has none")
+	assert.Equal(t, []ConfigFile{{Path: "/home/test/work/platform/sevp.d/10-aws.toml", Layer: LayerUser}}, cfg.Sources("aws"))
+	assert.Equal(t, []ConfigFile{
+		{Path: "/home/test/.config/sevp.toml", Layer: LayerUser},
+		{Path: "/home/test/work/platform/sevp.d/10-aws.toml", Layer: LayerUser},
+		{Path: "/home/test/work/platform/common.toml", Layer: LayerUser},
+		{Path: "/home/test/work/platform/sevp.d/20-kube.toml", Layer: LayerUser},
+		{Path: "/home/test/.config/local.toml", Layer: LayerUser},
+	}, cfg.Files, "files should be listed in include order")
+}
+
+// Include cycles, missing files and duplicate selectors should cause clear errors
+func TestIncludeErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		expectedErr string
+	}{
+		{
+			name: "Include Cycle",
+			files: map[string]string{
+				"/home/test/sevp.toml": `include = ["a.toml"]`,
+				"/home/test/a.toml":    `include = ["b.toml"]`,
+				"/home/test/b.toml":    `include = ["a.toml"]`,
+			},
+			expectedErr: "include cycle: /home/test/a.toml -> /home/test/b.toml -> /home/test/a.toml",
+		},
+		{
+			name: "Self Include",
+			files: map[string]string{
+				"/home/test/sevp.toml": `include = ["sevp.toml"]`,
+			},
+			expectedErr: "include cycle: /home/test/sevp.toml -> /home/test/sevp.toml",
+		},
+		{
+			name: "Missing File",
+			files: map[string]string{
+				"/home/test/sevp.toml": `include = ["missing.toml"]`,
+			},
+			expectedErr: `/home/test/sevp.toml: include "missing.toml": file /home/test/missing.toml does not exist`,
+		},
+		{
+			name: "Duplicate Selector",
+			files: map[string]string{
+				"/home/test/sevp.toml": `
+include = ["a.toml"]
+
+[aws]
+target_var = "AWS_PROFILE"
+possible_values = ["a"]
+`,
+				"/home/test/a.toml": `
+[aws]
+target_var = "AWS_PROFILE"
+possible_values = ["b"]
+`,
+			},
+			expectedErr: `duplicate selector "aws" in /home/test/sevp.toml and /home/test/a.toml`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := newTestRoot()
+			writeTestFiles(t, root, test.files)
+
+			_, err := LoadConfig(root, "/home/test/sevp.toml")
+			assert.Error(t, err, "expected error loading config")
+			assert.Contains(t, err.Error(), test.expectedErr)
+		})
+	}
+}
+
+// A missing include should not be mistaken for a missing optional user config
+func TestIncludeMissingInUserConfig(t *testing.T) {
+	root := newTestRoot()
+	t.Setenv("SEVP_SYSTEM_CONFIG", "")
+	t.Setenv("SEVP_TEAM_CONFIG", "")
+	writeTestFiles(t, root, map[string]string{
+		"/home/test/.config/sevp.toml": `include = ["missing.toml"]`,
+	})
+
+	_, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	assert.Error(t, err, "expected error for a missing include")
+	assert.NotErrorIs(t, err, ErrDefaultConfigCreated)
+}
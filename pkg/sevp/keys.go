@@ -0,0 +1,148 @@
+package sevp
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// Built-in key bindings, see Keys.
+const (
+	KeysDefault = "default"
+	KeysVim     = "vim"
+	KeysEmacs   = "emacs"
+)
+
+// KeyPresets are the names of the built-in key bindings, the first one is the default.
+var KeyPresets = []string{KeysDefault, KeysVim, KeysEmacs}
+
+// Keys configures the key bindings of the TUI, set in the [keys] table of the config.
+//
+// Key bindings start from a built-in preset, whose bindings can be replaced action by action.
+// Each action is bound to a list of keys named like "enter", "ctrl+c" or "alt+v", an empty list disables the action.
+// Keys are merged key by key across all layers, just like options.
+type Keys struct {
+	// Preset is the built-in key bindings the bindings are based on, see KeyPresets. Empty means KeysDefault.
+	Preset string `mapstructure:"preset" description:"The built-in key bindings the bindings are based on: default, vim or emacs. Defaults to default."`
+
+	// Pick picks the selected item.
+	Pick []string `mapstructure:"pick" description:"Keys picking the selected value."`
+
+	// Quit quits without picking an item.
+	Quit []string `mapstructure:"quit" description:"Keys quitting without picking a value."`
+
+	// Back returns from the value list to the selector list.
+	Back []string `mapstructure:"back" description:"Keys returning from the value list to the selector list."`
+
+	// Up moves the cursor up.
+	Up []string `mapstructure:"up" description:"Keys moving the cursor up."`
+
+	// Down moves the cursor down.
+	Down []string `mapstructure:"down" description:"Keys moving the cursor down."`
+
+	// PrevPage shows the previous page of the list.
+	PrevPage []string `mapstructure:"prev_page" description:"Keys showing the previous page of the list."`
+
+	// NextPage shows the next page of the list.
+	NextPage []string `mapstructure:"next_page" description:"Keys showing the next page of the list."`
+
+	// First moves the cursor to the first item.
+	First []string `mapstructure:"first" description:"Keys moving the cursor to the first value."`
+
+	// Last moves the cursor to the last item.
+	Last []string `mapstructure:"last" description:"Keys moving the cursor to the last value."`
+
+	// Search starts searching the list.
+	Search []string `mapstructure:"search" description:"Keys starting to search the list."`
+
+	// Help shows and hides the full help.
+	Help []string `mapstructure:"help" description:"Keys showing and hiding the full help."`
+
+	// Pin pins and unpins the selected item.
+	Pin []string `mapstructure:"pin" description:"Keys pinning and unpinning the selected value."`
+
+	// Preview shows and hides the preview pane.
+	Preview []string `mapstructure:"preview" description:"Keys showing and hiding the preview pane."`
+
+	// Unset removes the target variable of the selector.
+	Unset []string `mapstructure:"unset" description:"Keys unsetting the target variable of the selector."`
+
+	// Copy copies the value of the selected item to the clipboard.
+	Copy []string `mapstructure:"copy" description:"Keys copying the selected value to the clipboard."`
+
+	// Confirm confirms picking a dangerous item, any other key cancels.
+	Confirm []string `mapstructure:"confirm" description:"Keys confirming to pick a dangerous value, any other key cancels."`
+}
+
+// decodeKeys decodes the raw values of the keys table, rejecting unknown keys, presets, empty key names
+// and disabling confirm.
+//
+// Single keys are accepted in place of lists, e.g. pin = "p".
+func decodeKeys(raw map[string]any) (Keys, error) {
+	var keys Keys
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		ErrorUnused:      true,
+		WeaklyTypedInput: true,
+		Result:           &keys,
+	})
+	if err != nil {
+		return keys, err
+	}
+
+	if err := decoder.Decode(raw); err != nil {
+		return keys, fmt.Errorf("invalid keys: %w", err)
+	}
+
+	// dangerous items could never be picked in the TUI without a key confirming them
+	if keys.Confirm != nil && len(keys.Confirm) == 0 {
+		return keys, fmt.Errorf("invalid keys: confirm can't be disabled")
+	}
+
+	if keys.Preset != "" && !slices.Contains(KeyPresets, keys.Preset) {
+		return keys, fmt.Errorf("invalid keys: unknown preset %q (known presets: %s)", keys.Preset, strings.Join(KeyPresets, ", "))
+	}
+
+	actions := make([]string, 0, len(raw))
+	for action := range raw {
+		if action != "preset" {
+			actions = append(actions, action)
+		}
+	}
+	sort.Strings(actions)
+
+	for _, action := range actions {
+		for _, name := range toSlice(raw[action]) {
+			if strings.TrimSpace(fmt.Sprint(name)) == "" {
+				return keys, fmt.Errorf("invalid keys: %s: empty key name", action)
+			}
+		}
+	}
+
+	return keys, nil
+}
+
+// mergeKeys merges the raw keys of another config into the keys of the config.
+//
+// Keys already set are only replaced if override is set.
+func (c *Config) mergeKeys(raw map[string]any, override bool) {
+	if len(raw) == 0 {
+		return
+	}
+
+	if c.rawKeys == nil {
+		c.rawKeys = make(map[string]any)
+	}
+	for key, value := range raw {
+		if _, ok := c.rawKeys[key]; ok && !override {
+			continue
+		}
+		c.rawKeys[key] = value
+	}
+
+	// the keys of every file were decoded successfully when they were read, so the merged ones decode as well
+	c.Keys, _ = decodeKeys(c.rawKeys)
+}
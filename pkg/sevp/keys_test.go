@@ -0,0 +1,76 @@
+package sevp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The keys table should be decoded, accepting single keys and rejecting unknown keys, presets, empty key names and disabling confirm
+func TestReadKeys(t *testing.T) {
+	cfg, err := ReadConfig(strings.NewReader(`
+[keys]
+preset = "vim"
+pick = ["enter", "l"]
+pin = "m"
+unset = []
+confirm = "enter"
+
+[kube]
+target_var = "KUBECONFIG"
+possible_values = ["dev"]
+`))
+	require.NoError(t, err)
+	assert.Equal(t, Keys{Preset: KeysVim, Pick: []string{"enter", "l"}, Pin: []string{"m"}, Unset: []string{}, Confirm: []string{"enter"}}, cfg.Keys)
+	assert.NotNil(t, cfg.Keys.Unset, "an empty list disables the action")
+	assert.Equal(t, []string{"kube"}, cfg.Names(), "keys is not a selector")
+
+	tests := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{"unknown action", "[keys]\njump = \"j\"\n", "invalid keys"},
+		{"unknown preset", "[keys]\npreset = \"nano\"\n", `unknown preset "nano" (known presets: default, vim, emacs)`},
+		{"empty key name", "[keys]\nquit = [\"q\", \"\"]\n", "quit: empty key name"},
+		{"confirm disabled", "[keys]\nconfirm = []\n", "confirm can't be disabled"},
+		{"not a table", "keys = \"vim\"\n", "keys must be a table"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadConfig(strings.NewReader(tt.config))
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+// Keys should be merged action by action across layers
+func TestKeysLayers(t *testing.T) {
+	root := newTestRoot()
+	root.Workdir = "/home/test/repo"
+	t.Setenv("SEVP_SYSTEM_CONFIG", "/etc/sevp/sevp.toml")
+	t.Setenv("SEVP_TEAM_CONFIG", "")
+
+	writeTestFiles(t, root, map[string]string{
+		"/etc/sevp/sevp.toml": `
+[keys]
+preset = "emacs"
+copy = "alt+w"
+`,
+		"/home/test/.config/sevp.toml": `
+[keys]
+copy = ["c", "alt+w"]
+
+[kube]
+target_var = "KUBECONFIG"
+possible_values = ["dev"]
+`,
+	})
+
+	cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	require.NoError(t, err)
+	assert.Equal(t, Keys{Preset: KeysEmacs, Copy: []string{"c", "alt+w"}}, cfg.Keys)
+}
@@ -0,0 +1,385 @@
+package sevp
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Layer is the position of a config file in the configuration hierarchy.
+//
+// Config files are layered on top of each other from the lowest to the highest layer:
+//   - system: company-wide selectors, /etc/sevp/sevp.toml or $SEVP_SYSTEM_CONFIG
+//   - team: selectors shared by a team, $SEVP_TEAM_CONFIG
+//   - user: the user's own selectors, see ConfigPath
+//   - project: selectors of the current repository, see FindProjectConfigs
+type Layer int
+
+const (
+	LayerSystem Layer = iota
+	LayerTeam
+	LayerUser
+	LayerProject
+)
+
+// String returns the name of the layer.
+func (l Layer) String() string {
+	switch l {
+	case LayerSystem:
+		return "system"
+	case LayerTeam:
+		return "team"
+	case LayerUser:
+		return "user"
+	case LayerProject:
+		return "project"
+	default:
+		return fmt.Sprintf("Layer(%d)", int(l))
+	}
+}
+
+// ConfigFile is a config file and the layer it was loaded as.
+type ConfigFile struct {
+	Path  string
+	Layer Layer
+}
+
+// String returns the path and the layer of the config file.
+func (f ConfigFile) String() string {
+	return fmt.Sprintf("%s (%s)", f.Path, f.Layer)
+}
+
+const (
+	// MergeReplace replaces the possible values of lower layers. This is the default.
+	MergeReplace = "replace"
+
+	// MergeAppend appends the possible values to the ones of lower layers.
+	MergeAppend = "append"
+
+	// lockAll is the key under which a lock of all keys of a section is stored.
+	lockAll = "*"
+)
+
+// SystemConfigPath returns the path of the system config: $SEVP_SYSTEM_CONFIG or /etc/sevp/sevp.toml.
+func SystemConfigPath() string {
+	if envPath := os.Getenv("SEVP_SYSTEM_CONFIG"); envPath != "" {
+		return envPath
+	}
+
+	return "/etc/sevp/sevp.toml"
+}
+
+// TeamConfigPath returns the path of the team config: $SEVP_TEAM_CONFIG, or empty if it is not set.
+func TeamConfigPath() string {
+	return os.Getenv("SEVP_TEAM_CONFIG")
+}
+
+// section is a selector section of the config, merged across all layers.
+type section struct {
+	// raw holds the merged values of the section keys.
+	raw map[string]any
+
+	// merge is the merge mode of the possible values of the section: MergeReplace or MergeAppend.
+	merge string
+
+	// locked maps the locked keys to the file they were locked in.
+	locked map[string]ConfigFile
+
+	// sources are the files that defined or changed the section.
+	sources []ConfigFile
+
+	// line is the line the section was defined on in its first source.
+	line int
+
+	// locations maps the keys of the section to where they were last set.
+	locations map[string]location
+
+	// projectValues maps the keys of the section to the values that came from project configs,
+	// which must not run commands, see expander.
+	projectValues map[string]map[string]bool
+
+	// selector is the decoded and expanded selector, or the error decoding it, cached by Config.FromConfig.
+	selector    *ConfigSelector
+	selectorErr error
+}
+
+// location is a position in a config file.
+type location struct {
+	file string
+	line int
+}
+
+// newSection creates a section from the raw values of a single config file.
+//
+// The merge and locked keys control how the section is layered and are not part of the raw values.
+func newSection(raw map[string]any) (*section, error) {
+	s := &section{
+		raw:           make(map[string]any),
+		merge:         MergeReplace,
+		locked:        make(map[string]ConfigFile),
+		locations:     make(map[string]location),
+		projectValues: make(map[string]map[string]bool),
+	}
+
+	for key, value := range raw {
+		switch key {
+		case "merge":
+			mode, ok := value.(string)
+			if !ok || (mode != MergeReplace && mode != MergeAppend) {
+				return nil, fmt.Errorf("merge must be %q or %q, got %v", MergeReplace, MergeAppend, value)
+			}
+			s.merge = mode
+		case "locked":
+			switch locked := value.(type) {
+			case bool:
+				if locked {
+					s.locked[lockAll] = ConfigFile{}
+				}
+			case []any:
+				for _, key := range locked {
+					s.locked[fmt.Sprint(key)] = ConfigFile{}
+				}
+			default:
+				return nil, fmt.Errorf("locked must be a boolean or a list of keys, got %v", value)
+			}
+		default:
+			s.raw[key] = value
+		}
+	}
+
+	return s, nil
+}
+
+// decode decodes the merged raw values of the section into a ConfigSelector.
+func (s *section) decode(name string) (*ConfigSelector, error) {
+	return decodeSelector(name, s.raw)
+}
+
+// lockedBy returns the file the key was locked in, if it is locked.
+func (s *section) lockedBy(key string) (ConfigFile, bool) {
+	if file, ok := s.locked[key]; ok {
+		return file, true
+	}
+
+	file, ok := s.locked[lockAll]
+	return file, ok
+}
+
+// layer merges the section of a higher layer on top of the section.
+//
+// Keys set in the higher section replace the keys of the section, except for locked keys, which are kept.
+// The possible values are appended instead if the higher section uses MergeAppend.
+// Project configs come with the repositories they are in, so they can only add possible values and dangerous patterns
+// to selectors of other layers: they can't change what the selectors export or turn off their protection.
+func (s *section) layer(name string, higher *section) {
+	fromProject := higher.inProject() && !s.inProject()
+
+	for key, value := range higher.raw {
+		if lockedBy, ok := s.lockedBy(key); ok {
+			slog.Warn("Ignoring locked config key", "selector", name, "key", key, "locked_in", lockedBy.String())
+			continue
+		}
+
+		if fromProject && key != "possible_values" && key != "dangerous" {
+			slog.Warn("Ignoring project config key of a selector of another layer", "selector", name, "key", key, "path", higher.sources[0].Path)
+			continue
+		}
+
+		s.locations[key] = higher.locations[key]
+
+		if key == "possible_values" && (higher.merge == MergeAppend || fromProject) {
+			s.raw[key] = appendValues(s.raw[key], value)
+			for v := range higher.projectValues[key] {
+				if s.projectValues[key] == nil {
+					s.projectValues[key] = make(map[string]bool)
+				}
+				s.projectValues[key][v] = true
+			}
+			continue
+		}
+
+		if key == "dangerous" && fromProject {
+			s.raw[key] = appendValues(s.raw[key], value)
+			continue
+		}
+
+		s.raw[key] = value
+		s.projectValues[key] = higher.projectValues[key]
+	}
+
+	for key, file := range higher.locked {
+		if _, ok := s.locked[key]; !ok {
+			s.locked[key] = file
+		}
+	}
+
+	s.sources = append(s.sources, higher.sources...)
+	s.selector, s.selectorErr = nil, nil
+}
+
+// inProject reports whether the section was first defined in a project config.
+func (s *section) inProject() bool {
+	return len(s.sources) > 0 && s.sources[0].Layer == LayerProject
+}
+
+// appendValues appends the values to the existing values, skipping duplicates.
+func appendValues(existing any, values any) []any {
+	var result []any
+	seen := make(map[string]bool)
+
+	for _, v := range append(toSlice(existing), toSlice(values)...) {
+		if key := fmt.Sprint(v); !seen[key] {
+			seen[key] = true
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
+
+// toSlice wraps single values into a slice, just like the weakly typed decoding of possible values does.
+func toSlice(value any) []any {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []any:
+		return v
+	default:
+		return []any{v}
+	}
+}
+
+// setSource records the file all sections and the default selector of the config were read from.
+func (c *Config) setSource(file ConfigFile) {
+	c.Files = []ConfigFile{file}
+
+	if c.Default != "" {
+		c.defaultSource = file
+	}
+
+	for i := range c.diagnostics {
+		c.diagnostics[i].File = file.Path
+	}
+
+	// project configs come with the repositories they are in, so they must not run commands
+	if _, ok := c.rawOptions["allow_commands"]; ok && file.Layer == LayerProject {
+		slog.Warn("Ignoring allow_commands in project config", "path", file.Path)
+		delete(c.rawOptions, "allow_commands")
+		c.Options.AllowCommands = false
+	}
+
+	if stateFile, ok := c.rawOptions["state_file"].(string); ok && stateFile != "" {
+		switch {
+		// project configs must not write outside of their repositories
+		case file.Layer == LayerProject && !filepath.IsLocal(stateFile):
+			slog.Warn("Ignoring state_file outside of the project directory", "path", file.Path, "state_file", stateFile)
+			delete(c.rawOptions, "state_file")
+			c.Options.StateFile = ""
+		case !filepath.IsAbs(stateFile):
+			c.rawOptions["state_file"] = filepath.Join(filepath.Dir(file.Path), stateFile)
+			c.Options.StateFile = c.rawOptions["state_file"].(string)
+		}
+	}
+
+	for key, loc := range c.setLocations {
+		c.setLocations[key] = location{file: file.Path, line: loc.line}
+	}
+
+	for key, loc := range c.valueLocations {
+		c.valueLocations[key] = location{file: file.Path, line: loc.line}
+	}
+
+	// values are picked when entering the directory of a project, so they have no meaning in other layers
+	if len(c.values) > 0 && file.Layer != LayerProject {
+		for _, selector := range sortedKeys(c.values) {
+			d := c.valueDiagnostic(selector)
+			d.Message = "values are only applied from project configs, ignoring"
+			c.diagnostics = append(c.diagnostics, d)
+		}
+		c.values, c.valueLocations = nil, nil
+	}
+
+	for _, s := range c.sections {
+		s.sources = []ConfigFile{file}
+
+		// project configs come with the repositories they are in, so their values must not run commands
+		// even if the user allows commands
+		if file.Layer == LayerProject {
+			for key, value := range s.raw {
+				s.projectValues[key] = make(map[string]bool)
+				for _, v := range toSlice(value) {
+					s.projectValues[key][fmt.Sprint(v)] = true
+				}
+			}
+		}
+
+		for key := range s.locked {
+			s.locked[key] = file
+		}
+		for key, loc := range s.locations {
+			s.locations[key] = location{file: file.Path, line: loc.line}
+		}
+	}
+}
+
+// merge layers the other config on top of the config.
+//
+// Sections of the other config are merged key by key into the sections of the same name, see section.layer.
+// The default selector of the other config replaces the default selector if it is set.
+func (c *Config) merge(other *Config) {
+	if other.Default != "" {
+		c.Default = other.Default
+		c.defaultSource = other.defaultSource
+		c.defaultLine = other.defaultLine
+	}
+
+	for name, higher := range other.sections {
+		if s, ok := c.sections[name]; ok {
+			s.layer(name, higher)
+			continue
+		}
+		c.sections[name] = higher
+	}
+
+	c.mergeOptions(other.rawOptions, true)
+	c.mergeTheme(other.rawTheme, true)
+	c.mergeKeys(other.rawKeys, true)
+	c.mergeSets(other, true)
+	c.mergeValues(other, true)
+	c.Files = append(c.Files, other.Files...)
+	c.diagnostics = append(c.diagnostics, other.diagnostics...)
+}
+
+// Sources returns the config files that defined or changed the named selector, from the lowest to the highest layer.
+func (c *Config) Sources(name string) []ConfigFile {
+	if s, ok := c.sections[name]; ok {
+		return s.sources
+	}
+
+	return nil
+}
+
+// DefaultSource returns the config file the default selector was set in.
+func (c *Config) DefaultSource() ConfigFile {
+	return c.defaultSource
+}
+
+// LockedKeys returns the sorted keys of the named selector that are locked against changes by higher layers.
+//
+// A lock of all keys is returned as "*".
+func (c *Config) LockedKeys(name string) []string {
+	s, ok := c.sections[name]
+	if !ok {
+		return nil
+	}
+
+	keys := make([]string, 0, len(s.locked))
+	for key := range s.locked {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,235 @@
+package sevp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestFiles writes the files with their contents to the root
+func writeTestFiles(t *testing.T, root *Root, files map[string]string) {
+	t.Helper()
+
+	for filePath, content := range files {
+		assert.NoError(t, afero.WriteFile(root.Fs, filePath, []byte(content), 0600))
+	}
+}
+
+// The system, team, user and project layers should be merged key by key in order
+func TestLayeredConfig(t *testing.T) {
+	root := newTestRoot()
+	root.Workdir = "/home/test/repo"
+	t.Setenv("SEVP_SYSTEM_CONFIG", "")
+	t.Setenv("SEVP_TEAM_CONFIG", "/home/test/team/sevp.toml")
+
+	writeTestFiles(t, root, map[string]string{
+		"/etc/sevp/sevp.toml": `
+default = "aws"
+
+[aws]
+target_var = "AWS_PROFILE"
+possible_values = ["company-sso"]
+locked = ["target_var"]
+
+[region]
+target_var = "AWS_REGION"
+possible_values = ["eu-west-1"]
+locked = true
+`,
+		"/home/test/team/sevp.toml": `
+[aws]
+merge = "append"
+possible_values = ["team-dev", "team-prod"]
+`,
+		"/home/test/.config/sevp.toml": `
+[aws]
+target_var = "MY_PROFILE"
+merge = "append"
+possible_values = ["personal", "team-dev"]
+
+[region]
+possible_values = ["us-east-1"]
+`,
+		"/home/test/repo/.git/HEAD": "",
+		"/home/test/repo/.sevp.toml": `
+default = "kube"
+
+[kube]
+target_var = "KUBECONFIG"
+possible_values = ["dev.yaml"]
+`,
+	})
+
+	cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	assert.NoError(t, err, "expected no error initializing config")
+
+	selectors, err := cfg.ParseSelectors()
+	assert.NoError(t, err, "expected no error parsing selectors")
+
+	// locked target_var is kept, possible values are appended without duplicates
+	assert.Equal(t, "AWS_PROFILE", selectors["aws"].TargetVar)
+	assert.Equal(t, []string{"company-sso", "team-dev", "team-prod", "personal"}, selectors["aws"].PossibleValues)
+
+	// a fully locked selector ignores all changes
+	assert.Equal(t, []string{"eu-west-1"}, selectors["region"].PossibleValues)
+
+	assert.Equal(t, "kube", cfg.Default)
+	assert.Equal(t, ConfigFile{Path: "/home/test/repo/.sevp.toml", Layer: LayerProject}, cfg.DefaultSource())
+
+	assert.Equal(t, []ConfigFile{
+		{Path: "/etc/sevp/sevp.toml", Layer: LayerSystem},
+		{Path: "/home/test/team/sevp.toml", Layer: LayerTeam},
+		{Path: "/home/test/.config/sevp.toml", Layer: LayerUser},
+	}, cfg.Sources("aws"), "all files changing the selector should be listed")
+	assert.Equal(t, []ConfigFile{{Path: "/home/test/repo/.sevp.toml", Layer: LayerProject}}, cfg.Sources("kube"))
+
+	assert.Equal(t, []string{"target_var"}, cfg.LockedKeys("aws"))
+	assert.Equal(t, []string{"*"}, cfg.LockedKeys("region"))
+	assert.Empty(t, cfg.LockedKeys("kube"))
+}
+
+// Possible values of higher layers should replace the ones of lower layers by default
+func TestLayeredConfigReplace(t *testing.T) {
+	root := newTestRoot()
+	t.Setenv("SEVP_SYSTEM_CONFIG", "/home/test/system.toml")
+	t.Setenv("SEVP_TEAM_CONFIG", "")
+
+	writeTestFiles(t, root, map[string]string{
+		"/home/test/system.toml": `
+[aws]
+external_config = true
+target_var = "AWS_PROFILE"
+possible_values = ["company-sso"]
+`,
+		"/home/test/.config/sevp.toml": `
+[aws]
+external_config = false
+possible_values = ["personal"]
+`,
+	})
+
+	cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	assert.NoError(t, err, "expected no error initializing config")
+
+	selector, err := cfg.FromConfig("aws")
+	assert.NoError(t, err)
+	assert.Equal(t, &ConfigSelector{
+		Name:               "aws",
+		ReadExternalConfig: false,
+		TargetVar:          "AWS_PROFILE",
+		PossibleValues:     []string{"personal"},
+	}, selector, "keys not set in the higher layer should be kept")
+}
+
+// Project configs should only add possible values and dangerous patterns to selectors of other layers
+func TestLayeredConfigProject(t *testing.T) {
+	root := newTestRoot()
+	root.Workdir = "/home/test/repo"
+	t.Setenv("SEVP_SYSTEM_CONFIG", "")
+	t.Setenv("SEVP_TEAM_CONFIG", "")
+
+	writeTestFiles(t, root, map[string]string{
+		"/home/test/.config/sevp.toml": `
+[kube]
+target_var = "KUBECONFIG"
+value_template = "/kube/{{ .Value }}.yaml"
+possible_values = ["dev"]
+dangerous = ["prod"]
+
+[aws]
+external_config = true
+`,
+		"/home/test/repo/.git/HEAD": "",
+		"/home/test/repo/.sevp.toml": `
+[kube]
+target_var = "LD_PRELOAD"
+value_template = "/tmp/{{ .Value }}.so"
+merge = "replace"
+possible_values = ["acme"]
+dangerous = ["acme"]
+
+[aws]
+external_config = false
+target_var = "PATH"
+possible_values = ["/tmp"]
+
+[local]
+target_var = "LOCAL"
+value_template = "/local/{{ .Value }}"
+possible_values = ["a"]
+`,
+	})
+
+	cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	assert.NoError(t, err, "expected no error initializing config")
+
+	kube, err := cfg.FromConfig("kube")
+	assert.NoError(t, err)
+	assert.Equal(t, "KUBECONFIG", kube.TargetVar, "the target variable can't be overridden")
+	assert.Equal(t, "/kube/{{ .Value }}.yaml", kube.ValueTemplate, "the value template can't be overridden")
+	assert.Equal(t, []string{"dev", "acme"}, kube.PossibleValues, "possible values are added")
+	assert.Equal(t, []string{"prod", "acme"}, kube.Dangerous, "dangerous patterns are added")
+
+	aws, err := cfg.FromConfig("aws")
+	assert.NoError(t, err)
+	assert.True(t, aws.ReadExternalConfig, "external settings can't be overridden")
+	assert.Empty(t, aws.TargetVar)
+
+	local, err := cfg.FromConfig("local")
+	assert.NoError(t, err)
+	assert.Equal(t, &ConfigSelector{
+		Name:           "local",
+		TargetVar:      "LOCAL",
+		ValueTemplate:  "/local/{{ .Value }}",
+		PossibleValues: []string{"a"},
+	}, local, "selectors of project configs are not restricted")
+}
+
+// A default user config should not be created if a system config exists
+func TestLayeredConfigWithoutUserConfig(t *testing.T) {
+	root := newTestRoot()
+	t.Setenv("SEVP_SYSTEM_CONFIG", "")
+	t.Setenv("SEVP_TEAM_CONFIG", "")
+
+	writeTestFiles(t, root, map[string]string{
+		"/etc/sevp/sevp.toml": `
+[aws]
+target_var = "AWS_PROFILE"
+possible_values = ["company-sso"]
+`,
+	})
+
+	cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	assert.NoError(t, err, "expected no error without a user config")
+	assert.Equal(t, []string{"aws"}, cfg.Names())
+
+	exists, err := afero.Exists(root.Fs, "/home/test/.config/sevp.toml")
+	assert.NoError(t, err)
+	assert.False(t, exists, "no default config should be created")
+}
+
+// A missing team config or invalid merge settings should cause an error
+func TestLayeredConfigErrors(t *testing.T) {
+	root := newTestRoot()
+	t.Setenv("SEVP_SYSTEM_CONFIG", "")
+	t.Setenv("SEVP_TEAM_CONFIG", "/home/test/missing.toml")
+
+	_, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	assert.Error(t, err, "expected error for a missing team config")
+
+	_, err = ReadConfig(strings.NewReader(`
+[aws]
+merge = "prepend"
+`))
+	assert.Error(t, err, "expected error for an invalid merge mode")
+	assert.Contains(t, err.Error(), "merge")
+
+	_, err = ReadConfig(strings.NewReader(`
+[aws]
+locked = "yes"
+`))
+	assert.Error(t, err, "expected error for an invalid lock")
+	assert.Contains(t, err.Error(), "locked")
+}
@@ -0,0 +1,98 @@
+package sevp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/spf13/afero"
+)
+
+// stateLocks serialise the updates of state files within the process, keyed by path.
+// Files on the OS filesystem are additionally locked with an advisory lock to serialise them across processes.
+var stateLocks sync.Map
+
+// lockFile locks the file at the path exclusively until the returned function is called.
+//
+// The lock is held on a separate lock file next to it, so the file itself can be replaced by a rename.
+// The directory of the lock file is created if it doesn't exist.
+func lockFile(fs afero.Fs, path string) (func(), error) {
+	mu, _ := stateLocks.LoadOrStore(path, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+
+	lockPath := path + ".lock"
+	if err := fs.MkdirAll(filepath.Dir(lockPath), 0700); err != nil {
+		mu.(*sync.Mutex).Unlock()
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	f, err := fs.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		mu.(*sync.Mutex).Unlock()
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	// only files of the OS filesystem can be locked across processes
+	osFile := osFileOf(f)
+	if osFile != nil {
+		if err := flock(osFile); err != nil {
+			f.Close()
+			mu.(*sync.Mutex).Unlock()
+			return nil, fmt.Errorf("failed to lock %s: %w", lockPath, err)
+		}
+	}
+
+	return func() {
+		if osFile != nil {
+			_ = funlock(osFile)
+		}
+		f.Close()
+		mu.(*sync.Mutex).Unlock()
+	}, nil
+}
+
+// osFileOf returns the OS file of the file, or nil if it is not backed by one.
+func osFileOf(f afero.File) *os.File {
+	for {
+		switch file := f.(type) {
+		case *os.File:
+			return file
+		case *afero.BasePathFile:
+			f = file.File
+		default:
+			return nil
+		}
+	}
+}
+
+// writeFileAtomic writes the data to the file at the path by writing a temporary file next to it
+// and renaming it over the file, so readers see either the old or the new content, but never a partial write.
+func writeFileAtomic(fs afero.Fs, path string, data []byte, perm os.FileMode) error {
+	tmp, err := afero.TempFile(fs, filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	// the temporary file is removed unless it was renamed
+	defer fs.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := fs.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+
+	return fs.Rename(tmpPath, path)
+}
@@ -0,0 +1,15 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package sevp
+
+import "os"
+
+// flock is a no-op on platforms without advisory file locks, where updates are only serialised within the process.
+func flock(f *os.File) error {
+	return nil
+}
+
+// funlock is a no-op on platforms without advisory file locks.
+func funlock(f *os.File) error {
+	return nil
+}
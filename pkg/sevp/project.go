@@ -0,0 +1,53 @@
+package sevp
+
+import (
+	"path/filepath"
+
+	"github.com/spf13/afero"
+)
+
+// ProjectFileName is the name of project-local config files.
+// Project configs may also be in YAML or JSON, named .sevp.yaml, .sevp.yml or .sevp.json.
+const ProjectFileName = ".sevp.toml"
+
+// FindProjectConfigs returns the project config files found by walking up from the directory.
+//
+// Directory values files (see DirValuesFileName) are returned as well, right after the project config of their directory.
+//
+// The walk stops at the root of the repository (the first directory containing .git),
+// the home directory or the root of the filesystem, whichever comes first.
+// The files are ordered from the outermost to the innermost directory,
+// which is the order they are layered in.
+func FindProjectConfigs(root *Root, dir string) ([]string, error) {
+	var paths []string
+
+	for dir = filepath.Clean(dir); ; dir = filepath.Dir(dir) {
+		projectPath, exists, err := findConfigFile(root.Fs, filepath.Join(dir, ProjectFileName))
+		if err != nil {
+			return nil, err
+		}
+		valuesPath := filepath.Join(dir, DirValuesFileName)
+		hasValues, err := afero.Exists(root.Fs, valuesPath)
+		if err != nil {
+			return nil, err
+		}
+
+		var dirPaths []string
+		if exists {
+			dirPaths = append(dirPaths, projectPath)
+		}
+		if hasValues {
+			dirPaths = append(dirPaths, valuesPath)
+		}
+		paths = append(dirPaths, paths...)
+
+		isRepoRoot, err := afero.Exists(root.Fs, filepath.Join(dir, ".git"))
+		if err != nil {
+			return nil, err
+		}
+
+		if isRepoRoot || dir == filepath.Clean(root.Home) || dir == filepath.Dir(dir) {
+			return paths, nil
+		}
+	}
+}
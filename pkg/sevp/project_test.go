@@ -0,0 +1,43 @@
+package sevp
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+// Project configs should be found up to the repository root, outermost first
+func TestFindProjectConfigs(t *testing.T) {
+	root := newTestRoot()
+	for _, filePath := range []string{
+		"/home/test/.sevp.toml",
+		"/home/test/repo/.sevp.toml",
+		"/home/test/repo/a/b/.sevp.toml",
+	} {
+		assert.NoError(t, afero.WriteFile(root.Fs, filePath, []byte(""), 0600))
+	}
+	assert.NoError(t, root.Fs.MkdirAll("/home/test/repo/.git", 0750))
+	assert.NoError(t, root.Fs.MkdirAll("/home/test/repo/a/b/c", 0750))
+
+	paths, err := FindProjectConfigs(root, "/home/test/repo/a/b/c")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/home/test/repo/.sevp.toml", "/home/test/repo/a/b/.sevp.toml"}, paths,
+		"the walk should stop at the repository root")
+}
+
+// Outside of a repository, the walk should stop at the home directory
+func TestFindProjectConfigsOutsideRepository(t *testing.T) {
+	root := newTestRoot()
+	assert.NoError(t, afero.WriteFile(root.Fs, "/.sevp.toml", []byte(""), 0600))
+	assert.NoError(t, afero.WriteFile(root.Fs, "/home/test/.sevp.toml", []byte(""), 0600))
+	assert.NoError(t, root.Fs.MkdirAll("/home/test/projects/demo", 0750))
+
+	paths, err := FindProjectConfigs(root, "/home/test/projects/demo")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/home/test/.sevp.toml"}, paths)
+
+	paths, err = FindProjectConfigs(root, "/tmp")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/.sevp.toml"}, paths, "the walk should stop at the root of the filesystem")
+}
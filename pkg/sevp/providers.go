@@ -0,0 +1,61 @@
+package sevp
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/masamerc/sevp/pkg/sevp/extconfig"
+)
+
+// ProviderFunc creates a new external config provider reading from the given root.
+type ProviderFunc func(root *Root) Selector
+
+var (
+	providersMu sync.RWMutex
+
+	// providers maps the selector name to its external config provider.
+	providers = map[string]ProviderFunc{
+		"aws":            func(root *Root) Selector { return extconfig.NewAWSProfileSelector(root.Fs, root.Home) },
+		"docker-context": func(root *Root) Selector { return extconfig.NewDockerContextSelector(root.Fs, root.Home) },
+		"tfenv":          func(root *Root) Selector { return extconfig.NewTfEnvSelector(root.Fs, root.Home) },
+		"goenv":          func(root *Root) Selector { return extconfig.NewGoEnvSelector(root.Fs, root.Home) },
+	}
+)
+
+// RegisterProvider makes an external config provider available under the given selector name.
+//
+// Registering a name that is already taken replaces the existing provider.
+func RegisterProvider(name string, fn ProviderFunc) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	providers[name] = fn
+}
+
+// Providers returns the sorted names of all registered external config providers.
+func Providers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// GetExternalConfigSelector returns the appropriate Selector implementation based on the selectorName.
+func GetExternalConfigSelector(root *Root, selectorName string) (Selector, error) {
+	providersMu.RLock()
+	fn, ok := providers[selectorName]
+	providersMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("the external config provider is not supported for selector %s", selectorName)
+	}
+
+	return fn(root), nil
+}
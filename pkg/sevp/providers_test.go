@@ -0,0 +1,49 @@
+package sevp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stubSelector is a Selector with fixed values used for testing
+type stubSelector struct{}
+
+func (s stubSelector) Read() (string, []string, error) {
+	return "STUB_VAR", []string{"a", "b"}, nil
+}
+
+// Built-in providers should be available by their selector name
+func TestGetExternalConfigSelector(t *testing.T) {
+	for _, name := range []string{"aws", "docker-context", "tfenv", "goenv"} {
+		t.Run(name, func(t *testing.T) {
+			selector, err := GetExternalConfigSelector(newTestRoot(), name)
+			assert.NoError(t, err, "expected no error getting provider")
+			assert.NotNil(t, selector, "expected a provider")
+		})
+	}
+
+	_, err := GetExternalConfigSelector(newTestRoot(), "unknown")
+	assert.Error(t, err, "expected error for unknown provider")
+	assert.Contains(t, err.Error(), "not supported", "error should mention the unsupported provider")
+}
+
+// Registered providers should be listed and usable
+func TestRegisterProvider(t *testing.T) {
+	RegisterProvider("stub", func(root *Root) Selector { return stubSelector{} })
+	defer func() {
+		providersMu.Lock()
+		delete(providers, "stub")
+		providersMu.Unlock()
+	}()
+
+	assert.Contains(t, Providers(), "stub", "registered provider should be listed")
+
+	selector, err := GetExternalConfigSelector(newTestRoot(), "stub")
+	assert.NoError(t, err, "expected no error getting registered provider")
+
+	targetVar, values, err := selector.Read()
+	assert.NoError(t, err, "expected no error reading registered provider")
+	assert.Equal(t, "STUB_VAR", targetVar)
+	assert.Equal(t, []string{"a", "b"}, values)
+}
@@ -0,0 +1,86 @@
+package sevp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+)
+
+// Root is the filesystem and home directory sevp operates in.
+//
+// All file access of sevp, i.e. reading the config, the external config providers and the state file,
+// goes through Root so it can be replaced with an in-memory filesystem or a sandbox directory.
+type Root struct {
+	// Fs is the filesystem used for all file access.
+	Fs afero.Fs
+
+	// Home is the home directory on Fs.
+	Home string
+
+	// Workdir is the working directory on Fs, from which project configs are discovered.
+	Workdir string
+
+	// StatePath is the path of the state file on Fs, or empty for the state file in Home, see StateFile.
+	StatePath string
+}
+
+// NewRoot creates a new Root for the given filesystem and home directory.
+// The home directory is also used as the working directory.
+func NewRoot(fs afero.Fs, home string) *Root {
+	return &Root{Fs: fs, Home: home, Workdir: home}
+}
+
+// OSRoot returns the Root for the OS filesystem, the current user's home directory and the current working directory.
+func OSRoot() (*Root, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user home directory: %w", err)
+	}
+
+	workdir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	root := NewRoot(afero.NewOsFs(), home)
+	root.Workdir = workdir
+
+	return root, nil
+}
+
+// SandboxRoot returns a Root confined to the given directory, which also acts as the home directory.
+//
+// Nothing outside of the directory is read or written, which makes it useful for demos and tests.
+// If the current working directory is inside of the sandbox, it is used as the working directory of the root.
+func SandboxRoot(dir string) *Root {
+	root := NewRoot(afero.NewBasePathFs(afero.NewOsFs(), dir), string(filepath.Separator))
+
+	if workdir, err := os.Getwd(); err == nil {
+		if absDir, err := filepath.Abs(dir); err == nil {
+			if rel, err := filepath.Rel(absDir, workdir); err == nil && filepath.IsLocal(rel) {
+				root.Workdir = root.HomePath(rel)
+			}
+		}
+	}
+
+	return root
+}
+
+// OSPath returns the path of the file at the path on Fs in the OS filesystem, e.g. for shell code.
+// Paths of sandbox roots are resolved to their real path, paths of other filesystems are returned as is.
+func (r *Root) OSPath(path string) string {
+	if fs, ok := r.Fs.(*afero.BasePathFs); ok {
+		if osPath, err := fs.RealPath(path); err == nil {
+			return osPath
+		}
+	}
+
+	return path
+}
+
+// HomePath joins the path elements to the home directory.
+func (r *Root) HomePath(elem ...string) string {
+	return filepath.Join(append([]string{r.Home}, elem...)...)
+}
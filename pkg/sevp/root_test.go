@@ -0,0 +1,63 @@
+package sevp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestRoot returns an in-memory root with /home/test as the home directory
+func newTestRoot() *Root {
+	return NewRoot(afero.NewMemMapFs(), "/home/test")
+}
+
+// Paths should be joined to the home directory of the root
+func TestHomePath(t *testing.T) {
+	root := newTestRoot()
+	assert.Equal(t, "/home/test/.config/sevp.toml", root.HomePath(".config", "sevp.toml"))
+}
+
+// A sandbox root should read and write files inside the sandbox directory only
+func TestSandboxRoot(t *testing.T) {
+	sandbox := t.TempDir()
+	root := SandboxRoot(sandbox)
+
+	err := WriteToFile(root, "sandboxed", "TEST_VAR")
+	assert.NoError(t, err, "expected no error writing to the sandbox")
+
+	content, err := os.ReadFile(filepath.Join(sandbox, FileName))
+	assert.NoError(t, err, "expected the state file inside the sandbox")
+	assert.Contains(t, string(content), "export TEST_VAR='sandboxed'")
+
+	assert.Equal(t, filepath.Join(sandbox, FileName), root.OSPath(StateFile(root)), "paths resolve to the sandbox directory")
+	assert.Equal(t, "/home/test/.sevp", newTestRoot().OSPath("/home/test/.sevp"))
+}
+
+// Selectors and providers should read from the root of the config end to end
+func TestGetSelectorFromRoot(t *testing.T) {
+	t.Setenv("SEVP_CONFIG", "")
+	root := newTestRoot()
+	configContent := `
+default = "aws"
+
+[aws]
+external_config = true
+target_var = "AWS_PROFILE"
+`
+	assert.NoError(t, afero.WriteFile(root.Fs, ConfigPath(root, ""), []byte(configContent), 0600))
+	assert.NoError(t, afero.WriteFile(root.Fs, "/home/test/.aws/config", []byte("[profile dev]\n[profile prod]\n"), 0600))
+
+	cfg, err := LoadConfig(root, ConfigPath(root, ""))
+	assert.NoError(t, err, "expected no error loading config")
+
+	selector, err := cfg.GetSelector(root, nil)
+	assert.NoError(t, err, "expected no error getting selector")
+
+	targetVar, values, err := selector.Read()
+	assert.NoError(t, err, "expected no error reading selector")
+	assert.Equal(t, "AWS_PROFILE", targetVar)
+	assert.Equal(t, []string{"dev", "prod"}, values)
+}
@@ -0,0 +1,258 @@
+package sevp
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// schemaDraft is the JSON schema dialect of the generated schema.
+const schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema returns the JSON schema of the config format, e.g. for editors to complete and validate config files.
+//
+// The schemas of selectors and options are generated from the ConfigSelector and Options types the config is decoded into,
+// so it always matches what the loader accepts.
+func JSONSchema() ([]byte, error) {
+	selector, err := selectorSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := optionsSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	theme, err := themeSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := keysSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	properties := map[string]any{
+		"default": map[string]any{
+			"description": "The selector used when sevp is run without arguments.",
+			"type":        "string",
+		},
+		"include": map[string]any{
+			"description": "Config files to include. Globs and ~ are supported, relative paths are relative to the including file.",
+			"oneOf": []any{
+				map[string]any{"type": "string"},
+				map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			},
+		},
+		"options": options,
+		"theme":   theme,
+		"keys":    keys,
+		"sets": map[string]any{
+			"description": "Sets of selectors and the items to pick from them, applied at once with sevp use.",
+			"type":        "object",
+			"additionalProperties": map[string]any{
+				"type":                 "object",
+				"additionalProperties": map[string]any{"type": "string"},
+			},
+		},
+		"values": map[string]any{
+			"description":          "Selectors and the items picked from them when entering the directory. Only applied from project configs.",
+			"type":                 "object",
+			"additionalProperties": map[string]any{"type": "string"},
+		},
+	}
+
+	for _, name := range Providers() {
+		properties[name] = map[string]any{
+			"description": fmt.Sprintf("Selector that can read its values from the %s external config provider.", name),
+			"$ref":        "#/$defs/selector",
+		}
+	}
+
+	schema := map[string]any{
+		"$schema":              schemaDraft,
+		"title":                "sevp configuration",
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": map[string]any{"$ref": "#/$defs/selector"},
+		"$defs": map[string]any{
+			"selector": selector,
+		},
+	}
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// selectorSchema returns the JSON schema of a selector section.
+func selectorSchema() (map[string]any, error) {
+	properties, keys, err := structProperties(reflect.TypeOf(ConfigSelector{}))
+	if err != nil {
+		return nil, err
+	}
+
+	schema := map[string]any{
+		"merge": map[string]any{
+			"description": "How the values of this section are merged with the ones of lower config layers.",
+			"enum":        []string{MergeReplace, MergeAppend},
+		},
+		"locked": map[string]any{
+			"description": "Keys higher config layers can't change, or true to lock the whole selector.",
+			"oneOf": []any{
+				map[string]any{"type": "boolean"},
+				map[string]any{"type": "array", "items": map[string]any{"enum": keys}},
+			},
+		},
+	}
+	for key, property := range properties {
+		// lists are decoded weakly typed, so a single value is accepted in place of a list
+		if property["type"] == "array" {
+			property = singleOrList(property)
+		}
+		schema[key] = property
+	}
+
+	return map[string]any{
+		"type":                 "object",
+		"properties":           schema,
+		"additionalProperties": false,
+	}, nil
+}
+
+// singleOrList returns the schema of the array property that also accepts a single item in place of the list.
+func singleOrList(property map[string]any) map[string]any {
+	list := make(map[string]any, len(property))
+	for key, value := range property {
+		if key != "description" {
+			list[key] = value
+		}
+	}
+
+	return map[string]any{
+		"description": property["description"],
+		"oneOf":       []any{property["items"], list},
+	}
+}
+
+// optionsSchema returns the JSON schema of the options table.
+func optionsSchema() (map[string]any, error) {
+	properties, _, err := structProperties(reflect.TypeOf(Options{}))
+	if err != nil {
+		return nil, err
+	}
+
+	schema := make(map[string]any, len(properties))
+	for key, property := range properties {
+		schema[key] = property
+	}
+
+	return map[string]any{
+		"description":          "Global options.",
+		"type":                 "object",
+		"properties":           schema,
+		"additionalProperties": false,
+	}, nil
+}
+
+// themeSchema returns the JSON schema of the theme table.
+func themeSchema() (map[string]any, error) {
+	properties, _, err := structProperties(reflect.TypeOf(Theme{}))
+	if err != nil {
+		return nil, err
+	}
+
+	properties["preset"]["enum"] = ThemePresets
+
+	schema := make(map[string]any, len(properties))
+	for key, property := range properties {
+		schema[key] = property
+	}
+
+	return map[string]any{
+		"description":          "The colours of the TUI and the CLI output.",
+		"type":                 "object",
+		"properties":           schema,
+		"additionalProperties": false,
+	}, nil
+}
+
+// keysSchema returns the JSON schema of the keys table.
+func keysSchema() (map[string]any, error) {
+	properties, _, err := structProperties(reflect.TypeOf(Keys{}))
+	if err != nil {
+		return nil, err
+	}
+
+	schema := make(map[string]any, len(properties))
+	for key, property := range properties {
+		if key == "preset" {
+			property["enum"] = KeyPresets
+			schema[key] = property
+			continue
+		}
+
+		// actions are bound to a single key or a list of keys
+		schema[key] = singleOrList(property)
+	}
+
+	return map[string]any{
+		"description":          "The key bindings of the TUI.",
+		"type":                 "object",
+		"properties":           schema,
+		"additionalProperties": false,
+	}, nil
+}
+
+// structProperties returns the JSON schemas of the fields of a struct decoded with mapstructure,
+// documented by their description tags, and the keys of the fields in order.
+func structProperties(t reflect.Type) (map[string]map[string]any, []string, error) {
+	properties := make(map[string]map[string]any)
+	var keys []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		key := field.Tag.Get("mapstructure")
+		if key == "" || key == "-" {
+			continue
+		}
+		keys = append(keys, key)
+
+		property, err := typeSchema(field.Type)
+		if err != nil {
+			return nil, nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		property["description"] = field.Tag.Get("description")
+
+		properties[key] = property
+	}
+
+	return properties, keys, nil
+}
+
+// typeSchema returns the JSON schema of a Go type.
+func typeSchema(t reflect.Type) (map[string]any, error) {
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Map:
+		values, err := typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	case reflect.Slice:
+		items, err := typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
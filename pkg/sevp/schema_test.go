@@ -0,0 +1,54 @@
+package sevp
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The JSON schema should describe every key the loader accepts
+func TestJSONSchema(t *testing.T) {
+	data, err := JSONSchema()
+	require.NoError(t, err)
+
+	var schema struct {
+		Properties map[string]map[string]any `json:"properties"`
+		Defs       struct {
+			Selector struct {
+				Properties map[string]map[string]any `json:"properties"`
+			} `json:"selector"`
+		} `json:"$defs"`
+	}
+	require.NoError(t, json.Unmarshal(data, &schema))
+
+	selectorProperties := schema.Defs.Selector.Properties
+	keys := make([]string, 0, len(selectorProperties))
+	for key, property := range selectorProperties {
+		keys = append(keys, key)
+		assert.NotEmpty(t, property["description"], "key %s should be documented", key)
+	}
+	assert.ElementsMatch(t, selectorKeys(), keys)
+	for _, key := range []string{"possible_values", "dangerous"} {
+		assert.Equal(t, []any{
+			map[string]any{"type": "string"},
+			map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		}, selectorProperties[key]["oneOf"], "%s accepts a single value or a list", key)
+	}
+	assert.Equal(t, "boolean", selectorProperties["external_config"]["type"])
+
+	for _, key := range append([]string{"default", "include", "options", "theme", "keys", "sets", "values"}, Providers()...) {
+		assert.Contains(t, schema.Properties, key)
+	}
+
+	// every key of the default config should be known to the schema
+	cfg, err := ReadConfig(strings.NewReader(defaultConfig))
+	require.NoError(t, err)
+	for _, name := range cfg.Names() {
+		for key := range cfg.sections[name].raw {
+			assert.Contains(t, selectorProperties, key)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package sevp
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Sets switch several selectors at once. They are defined in the sets table of the config,
+// mapping selectors to the items to pick from them:
+//
+//	[sets.acme-prod]
+//	aws = "acme-prod"
+//	kube = "acme-prod-eks"
+//
+// Sets are merged key by key across all layers, just like selectors.
+
+// Assignment is an environment variable set by picking an item from a selector.
+type Assignment struct {
+	// Selector is the name of the selector.
+	Selector string
+
+	// TargetVar is the environment variable the value is written to.
+	TargetVar string
+
+	// Item is the picked item.
+	Item string
+
+	// Value is the value written for the item, see RenderValue.
+	Value string
+
+	// Dangerous marks items that need to be confirmed before they are picked, see ConfigSelector.IsDangerous.
+	Dangerous bool
+}
+
+// readSets reads the raw sets table of a config file.
+func readSets(value any) (map[string]map[string]string, error) {
+	table, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("sets must be a table of sets, got %v", value)
+	}
+
+	sets := make(map[string]map[string]string)
+	for name, value := range table {
+		set, err := readItems(value)
+		if err != nil {
+			return nil, fmt.Errorf("set %s: %w", name, err)
+		}
+		sets[name] = set
+	}
+
+	return sets, nil
+}
+
+// readItems reads a raw table mapping selectors to the items to pick from them.
+func readItems(value any) (map[string]string, error) {
+	table, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("must be a table of selectors and items, got %v", value)
+	}
+
+	items := make(map[string]string)
+	for selector, value := range table {
+		item, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("the item of selector %s must be a string, got %v", selector, value)
+		}
+		items[selector] = item
+	}
+
+	return items, nil
+}
+
+// mergeSets merges the sets of another config into the sets of the config, selector by selector.
+//
+// Selectors already set are only replaced if override is set.
+func (c *Config) mergeSets(other *Config, override bool) {
+	for name, set := range other.sets {
+		if c.sets == nil {
+			c.sets = make(map[string]map[string]string)
+			c.setLocations = make(map[string]location)
+		}
+		if c.sets[name] == nil {
+			c.sets[name] = make(map[string]string)
+		}
+
+		for selector, item := range set {
+			if _, ok := c.sets[name][selector]; ok && !override {
+				continue
+			}
+			c.sets[name][selector] = item
+			c.setLocations[name+"."+selector] = other.setLocations[name+"."+selector]
+		}
+	}
+}
+
+// SetNames returns the sorted names of all sets defined in the config.
+func (c *Config) SetNames() []string {
+	names := make([]string, 0, len(c.sets))
+	for name := range c.sets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Set returns the named set, mapping selectors to the items to pick from them.
+//
+// Names are case insensitive, as keys are lowercased when the config is read.
+func (c *Config) Set(name string) (map[string]string, error) {
+	set, ok := c.sets[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("invalid set: %s - the set is not in the config", name)
+	}
+
+	return set, nil
+}
+
+// ResolveSet returns the assignments of the named set, ordered by selector.
+//
+// Every selector of the set is read, so the items can be checked against its possible values.
+// Nothing is returned unless the whole set resolves, so a set is either applied completely or not at all.
+func (c *Config) ResolveSet(root *Root, name string) ([]Assignment, error) {
+	set, err := c.Set(name)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments, err := c.resolveItems(root, set)
+	if err != nil {
+		return nil, fmt.Errorf("set %s: %w", name, err)
+	}
+
+	return assignments, nil
+}
+
+// resolveItems returns the assignments of the selectors and the items to pick from them, ordered by selector.
+func (c *Config) resolveItems(root *Root, items map[string]string) ([]Assignment, error) {
+	assignments := make([]Assignment, 0, len(items))
+	targets := make(map[string]string)
+
+	for _, selectorName := range sortedKeys(items) {
+		item := items[selectorName]
+
+		selector, err := c.GetSelector(root, []string{selectorName})
+		if err != nil {
+			return nil, err
+		}
+
+		targetVar, values, err := selector.Read()
+		if err != nil {
+			return nil, fmt.Errorf("selector %s: %w", selectorName, err)
+		}
+
+		if !slices.Contains(values, item) {
+			return nil, fmt.Errorf("%q is not a possible value of selector %s", item, selectorName)
+		}
+
+		if other, ok := targets[targetVar]; ok {
+			return nil, fmt.Errorf("selectors %s and %s both set %s", other, selectorName, targetVar)
+		}
+		targets[targetVar] = selectorName
+
+		value, err := RenderValue(selector, item)
+		if err != nil {
+			return nil, fmt.Errorf("selector %s: %w", selectorName, err)
+		}
+
+		assignments = append(assignments, Assignment{
+			Selector:  selectorName,
+			TargetVar: targetVar,
+			Item:      item,
+			Value:     value,
+			Dangerous: c.IsDangerous(selectorName, item),
+		})
+	}
+
+	return assignments, nil
+}
+
+// ApplySet resolves the named set and writes all of its assignments to the state file at once.
+func (c *Config) ApplySet(root *Root, name string) ([]Assignment, error) {
+	assignments, err := c.ResolveSet(root, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := WriteAssignments(root, "set "+strings.ToLower(name), assignments); err != nil {
+		return nil, err
+	}
+
+	return assignments, nil
+}
+
+// checkSets checks that the sets reference existing selectors and, where known, their possible values.
+func (c *Config) checkSets() []Diagnostic {
+	var diagnostics []Diagnostic
+
+	for _, name := range c.SetNames() {
+		diagnostics = append(diagnostics, c.checkItems(c.sets[name], func(selector string) Diagnostic {
+			return c.setDiagnostic(name, selector)
+		})...)
+	}
+
+	return diagnostics
+}
+
+// checkItems checks that the selectors exist and, where known, that the items are among their possible values.
+// The diagnostic function creates the diagnostic located where the item of a selector was set.
+func (c *Config) checkItems(items map[string]string, diagnostic func(selector string) Diagnostic) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	for _, selectorName := range sortedKeys(items) {
+		d := diagnostic(selectorName)
+
+		sec, ok := c.sections[selectorName]
+		if !ok {
+			d.Message = fmt.Sprintf("selector %q is not defined", selectorName)
+			diagnostics = append(diagnostics, d)
+			continue
+		}
+
+		// the values of external configs are only known at runtime
+		s, err := sec.decode(selectorName)
+		if err != nil || s.ReadExternalConfig {
+			continue
+		}
+
+		values := make([]string, 0, len(s.PossibleValues))
+		for _, value := range s.PossibleValues {
+			// values with unresolved references are reported by checkExpansion
+			if expanded, err := c.expander(selectorName, true).expandValue("possible_values", value); err == nil {
+				values = append(values, expanded)
+			}
+		}
+
+		if item := items[selectorName]; !slices.Contains(values, item) {
+			d.Message = fmt.Sprintf("%q is not a possible value of selector %s", item, selectorName)
+			diagnostics = append(diagnostics, d)
+		}
+	}
+
+	return diagnostics
+}
+
+// setDiagnostic creates a diagnostic located where the selector of the named set was last set.
+func (c *Config) setDiagnostic(name string, selector string) Diagnostic {
+	loc := c.setLocations[name+"."+selector]
+
+	return Diagnostic{File: loc.file, Line: loc.line, Key: "sets." + name + "." + selector}
+}
+
+// sortedKeys returns the sorted keys of the map.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
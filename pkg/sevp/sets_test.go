@@ -0,0 +1,119 @@
+package sevp
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setsTestRoot returns a root with a config defining selectors and sets, and an AWS config
+func setsTestRoot(t *testing.T) *Root {
+	t.Helper()
+	t.Setenv("SEVP_SYSTEM_CONFIG", "")
+	t.Setenv("SEVP_TEAM_CONFIG", "")
+
+	root := newTestRoot()
+	writeTestFiles(t, root, map[string]string{
+		"/home/test/.aws/config": "[profile acme-prod]\n[profile globex-staging]\n",
+		"/home/test/.config/sevp.toml": `
+[aws]
+external_config = true
+
+[kube]
+target_var = "KUBECONFIG"
+possible_values = ["acme-prod-eks", "globex-staging-gke"]
+value_template = "/kube/{{ .Value }}.yaml"
+
+[kube_alias]
+target_var = "KUBECONFIG"
+possible_values = ["acme-prod-eks"]
+
+[sets.acme-prod]
+aws = "acme-prod"
+kube = "acme-prod-eks"
+
+[sets.clash]
+kube = "acme-prod-eks"
+kube_alias = "acme-prod-eks"
+
+[sets.broken]
+aws = "acme-prod"
+kube = "nope"
+`,
+	})
+
+	return root
+}
+
+// Applying a set should write all of its values at once
+func TestApplySet(t *testing.T) {
+	root := setsTestRoot(t)
+
+	cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acme-prod", "broken", "clash"}, cfg.SetNames())
+
+	assignments, err := cfg.ApplySet(root, "ACME-prod")
+	require.NoError(t, err)
+	assert.Equal(t, []Assignment{
+		{Selector: "aws", TargetVar: "AWS_PROFILE", Item: "acme-prod", Value: "acme-prod"},
+		{Selector: "kube", TargetVar: "KUBECONFIG", Item: "acme-prod-eks", Value: "/kube/acme-prod-eks.yaml"},
+	}, assignments)
+
+	state, err := ReadState(root)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"AWS_PROFILE": "acme-prod", "KUBECONFIG": "/kube/acme-prod-eks.yaml"}, state)
+}
+
+// Sets that don't resolve completely should not write anything
+func TestApplySetErrors(t *testing.T) {
+	root := setsTestRoot(t)
+
+	cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	require.NoError(t, err)
+
+	_, err = cfg.ApplySet(root, "broken")
+	assert.ErrorContains(t, err, `set broken: "nope" is not a possible value of selector kube`)
+
+	_, err = cfg.ApplySet(root, "clash")
+	assert.ErrorContains(t, err, "set clash: selectors kube and kube_alias both set KUBECONFIG")
+
+	_, err = cfg.ApplySet(root, "missing")
+	assert.ErrorContains(t, err, "invalid set: missing - the set is not in the config")
+
+	exists, err := afero.Exists(root.Fs, StateFile(root))
+	require.NoError(t, err)
+	assert.False(t, exists, "nothing should be written for sets that don't resolve")
+}
+
+// Sets should be merged across layers and checked when validating
+func TestSetsLayersAndValidation(t *testing.T) {
+	root := setsTestRoot(t)
+	root.Workdir = "/home/test/repo"
+	writeTestFiles(t, root, map[string]string{
+		"/home/test/repo/.git/HEAD": "",
+		"/home/test/repo/.sevp.toml": `
+[sets.acme-prod]
+kube = "globex-staging-gke"
+gcp = "acme"
+`,
+	})
+
+	cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	require.NoError(t, err)
+
+	set, err := cfg.Set("acme-prod")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"aws": "acme-prod", "kube": "globex-staging-gke", "gcp": "acme"}, set)
+
+	var got []string
+	for _, diagnostic := range cfg.Validate() {
+		got = append(got, diagnostic.String())
+	}
+	assert.Equal(t, []string{
+		`/home/test/.config/sevp.toml:24: sets.broken.kube: "nope" is not a possible value of selector kube`,
+		`/home/test/repo/.sevp.toml:4: sets.acme-prod.gcp: selector "gcp" is not defined`,
+	}, got)
+}
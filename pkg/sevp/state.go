@@ -0,0 +1,530 @@
+package sevp
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	// FileName is the name of the state file in the user's home directory
+	// that the shell hook evaluates.
+	FileName = ".sevp"
+
+	// StateDirName is the name of the directory in the user's home directory that holds the metadata, history,
+	// usage and lock files of state files set with the state_file option, so they don't end up in projects.
+	StateDirName = ".sevp-state"
+
+	// StateVersion is the version of the format of the state metadata file.
+	StateVersion = 1
+)
+
+// Sources of state entries, see StateEntry.
+const (
+	// SourcePicker marks values picked in the TUI.
+	SourcePicker = "picker"
+
+	// SourceDirectory marks directory values, see SwitchDirValues.
+	SourceDirectory = "directory"
+
+	// SourceLibrary marks values written with WriteToFile or WriteValues.
+	SourceLibrary = "library"
+
+	// SourceUndo marks values restored by Undo.
+	SourceUndo = "undo"
+
+	// SourceBack marks values restored by Back.
+	SourceBack = "back"
+
+	// SourceUnknown marks values found in the state file without metadata,
+	// i.e. values written by earlier versions of sevp or by hand.
+	SourceUnknown = "unknown"
+)
+
+// now returns the current time of state entries, replaced in tests.
+var now = time.Now
+
+// StateEntry is an environment variable set by sevp, together with how and when it was set.
+type StateEntry struct {
+	// Var is the environment variable.
+	Var string `json:"var"`
+
+	// Value is the value of the variable.
+	Value string `json:"value"`
+
+	// Selector is the selector the value was picked from, if known.
+	Selector string `json:"selector,omitempty"`
+
+	// Item is the picked item the value was rendered from, see RenderValue.
+	Item string `json:"item,omitempty"`
+
+	// Source describes how the value was set: SourcePicker, "set <name>" for sets,
+	// SourceDirectory, SourceLibrary, SourceUndo, SourceBack or SourceUnknown.
+	Source string `json:"source"`
+
+	// Time is when the value was set.
+	Time time.Time `json:"time"`
+
+	// Verbatim reports whether the value is exported unquoted, as shell code. Values written by earlier versions
+	// of sevp or by hand are, e.g. `$HOME/.kube/dev`, and are kept as they are until the variable is set again.
+	Verbatim bool `json:"verbatim,omitempty"`
+}
+
+// State is the structured state of the environment variables set by sevp.
+//
+// It is stored as JSON in the metadata file next to the state file, see MetadataFile.
+// The state file itself is an export script rendered from it, which the shell hook evaluates.
+type State struct {
+	// Version is the version of the format, see StateVersion.
+	Version int `json:"version"`
+
+	// Entries are the variables, sorted by name.
+	Entries []StateEntry `json:"entries"`
+}
+
+// Values maps the variables of the state to their values.
+func (s *State) Values() map[string]string {
+	values := make(map[string]string, len(s.Entries))
+	for _, entry := range s.Entries {
+		values[entry.Var] = entry.Value
+	}
+
+	return values
+}
+
+// Entry returns the entry of the variable, if it is set.
+func (s *State) Entry(target string) (StateEntry, bool) {
+	for _, entry := range s.Entries {
+		if entry.Var == target {
+			return entry, true
+		}
+	}
+
+	return StateEntry{}, false
+}
+
+// Script renders the export script of the state for the shell, see SupportedShells.
+//
+// The values are single-quoted, so shells export them as literal strings and never run code in them.
+// Verbatim values are written as they were read from the state file.
+func (s *State) Script(shell string) (string, error) {
+	if !slices.Contains(SupportedShells, shell) {
+		return "", fmt.Errorf("unsupported shell: %s", shell)
+	}
+
+	var b strings.Builder
+	for _, entry := range s.Entries {
+		line, err := exportLine(entry.Var, entry.Value)
+		if err != nil {
+			return "", err
+		}
+		if entry.Verbatim {
+			line = "export " + entry.Var + "=" + entry.Value
+		}
+		b.WriteString(line + "\n")
+	}
+
+	return b.String(), nil
+}
+
+// set adds or replaces the entry of its variable, keeping the entries sorted.
+func (s *State) set(entry StateEntry) {
+	i, found := slices.BinarySearchFunc(s.Entries, entry.Var, func(e StateEntry, target string) int {
+		return strings.Compare(e.Var, target)
+	})
+	if found {
+		s.Entries[i] = entry
+		return
+	}
+
+	s.Entries = slices.Insert(s.Entries, i, entry)
+}
+
+// unset removes the entry of the variable.
+func (s *State) unset(target string) {
+	s.Entries = slices.DeleteFunc(s.Entries, func(e StateEntry) bool {
+		return e.Var == target
+	})
+}
+
+// StateFile returns the path of the state file of the root: its StatePath if set,
+// or the state file in its home directory otherwise.
+func StateFile(root *Root) string {
+	if root.StatePath != "" {
+		return filepath.Clean(root.StatePath)
+	}
+
+	return filepath.Clean(root.HomePath(FileName))
+}
+
+// MetadataFile returns the path of the metadata file of the state file of the root, which holds the structured State.
+func MetadataFile(root *Root) string {
+	return sidecarPath(root) + ".json"
+}
+
+// sidecarPath returns the path the metadata, history, usage and lock files of the state file of the root are named after.
+//
+// The files of the state file in the home directory are kept next to it. The ones of other state files are kept
+// in StateDirName, named after the base name and a hash of the path of the state file.
+func sidecarPath(root *Root) string {
+	path := StateFile(root)
+	if path == filepath.Clean(root.HomePath(FileName)) {
+		return path
+	}
+
+	sum := sha256.Sum256([]byte(path))
+	return root.HomePath(StateDirName, filepath.Base(path)+"-"+hex.EncodeToString(sum[:8]))
+}
+
+// ReadState reads the state file and returns the environment variables it exports.
+//
+// A missing state file is not an error and results in an empty map.
+func ReadState(root *Root) (map[string]string, error) {
+	state, err := LoadState(root)
+	if err != nil {
+		return nil, err
+	}
+
+	return state.Values(), nil
+}
+
+// LoadState reads the structured state of the root.
+//
+// The variables exported by the state file are authoritative, as they are what shells evaluate.
+// Their metadata is taken from the metadata file if it matches, so state files of earlier versions of sevp
+// and changes made by hand are read as entries of SourceUnknown. They are migrated on the next write.
+func LoadState(root *Root) (*State, error) {
+	state, _, err := loadState(root)
+	return state, err
+}
+
+// loadState reads the structured state of the root and the lines of the state file that are not exports of it.
+func loadState(root *Root) (*State, []string, error) {
+	filePath := StateFile(root)
+
+	lines, err := readLines(root.Fs, filePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, err
+	}
+
+	metadata, err := readMetadata(root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var modTime time.Time
+	if info, err := root.Fs.Stat(filePath); err == nil {
+		modTime = info.ModTime()
+	}
+
+	state := &State{Version: StateVersion}
+	var other []string
+
+	for _, line := range lines {
+		target, value, quoted, ok := parseExportLine(line)
+		if !ok {
+			other = append(other, line)
+			continue
+		}
+
+		entry, ok := metadata.Entry(target)
+		if !ok || entry.Value != value {
+			entry = StateEntry{Var: target, Value: value, Source: SourceUnknown, Time: modTime}
+		}
+		// the shell expands unquoted values, so they are kept as they are rather than quoted as literals
+		entry.Verbatim = !quoted
+		state.set(entry)
+	}
+
+	return state, other, nil
+}
+
+// readMetadata reads the metadata file of the root. A missing or invalid file results in an empty state.
+func readMetadata(root *Root) (*State, error) {
+	path := MetadataFile(root)
+
+	data, err := afero.ReadFile(root.Fs, path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &State{Version: StateVersion}, nil
+		}
+		return nil, err
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		// the state file still holds the values, only their metadata is lost
+		slog.Warn("Ignoring invalid state metadata file", "path", path, "err", err)
+		return &State{Version: StateVersion}, nil
+	}
+
+	if state.Version > StateVersion {
+		slog.Warn("State metadata file is of a newer version of sevp, ignoring it", "path", path, "version", state.Version)
+		return &State{Version: StateVersion}, nil
+	}
+
+	return &state, nil
+}
+
+// WriteToFile writes an environment variable to the state file of the root.
+func WriteToFile(root *Root, value string, target string) error {
+	return WriteValues(root, map[string]string{target: value})
+}
+
+// WriteValues writes environment variables, mapped to their values, to the state file of the root at once.
+//
+// The values are recorded as SourceLibrary, use WriteAssignments to record where they were picked from.
+func WriteValues(root *Root, values map[string]string) error {
+	entries := make([]StateEntry, 0, len(values))
+	for _, target := range sortedKeys(values) {
+		entries = append(entries, StateEntry{Var: target, Value: values[target], Source: SourceLibrary})
+	}
+
+	return updateState(root, stateUpdate{entries: entries})
+}
+
+// WriteAssignments writes the assignments to the state file of the root at once, recording the selectors
+// and items they were picked from and the source, e.g. SourcePicker.
+func WriteAssignments(root *Root, source string, assignments []Assignment) error {
+	entries := make([]StateEntry, 0, len(assignments))
+	for _, assignment := range assignments {
+		entries = append(entries, assignment.entry(source))
+	}
+
+	return updateState(root, stateUpdate{entries: entries})
+}
+
+// entry returns the state entry of the assignment.
+func (a Assignment) entry(source string) StateEntry {
+	return StateEntry{Var: a.TargetVar, Value: a.Value, Selector: a.Selector, Item: a.Item, Source: source}
+}
+
+// UnsetValues removes environment variables from the state file of the root.
+//
+// The variables are not unset in shells that already evaluated the state file.
+// The removals are recorded as SourceLibrary, use UnsetValuesFrom to record where they were made.
+func UnsetValues(root *Root, targets []string) error {
+	return UnsetValuesFrom(root, SourceLibrary, targets)
+}
+
+// UnsetValuesFrom removes environment variables from the state file of the root, recording the source, e.g. SourcePicker.
+func UnsetValuesFrom(root *Root, source string, targets []string) error {
+	return updateState(root, stateUpdate{unset: targets, source: source})
+}
+
+// stateUpdate is a change of the state, made at once and recorded as a single change in the history.
+type stateUpdate struct {
+	// entries are the entries to set. Entries without a time are set at the time of the update.
+	entries []StateEntry
+
+	// unset are the variables to remove.
+	unset []string
+
+	// source is the source recorded in the history for removing the unset variables, see StateEntry.
+	source string
+
+	// undoes is the time of the change the update reverts, see Undo.
+	undoes time.Time
+}
+
+// updateState applies the update to the state of the root.
+//
+// Updates are serialised with a lock, and the files are replaced atomically, so shells evaluating them never see a partial write.
+func updateState(root *Root, update stateUpdate) error {
+	unlock, err := lockFile(root.Fs, sidecarPath(root))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return rewriteState(root, update)
+}
+
+// rewriteState applies the update to the state of the root and records it in the history, see ReadHistory.
+// The state file must be locked, see updateState.
+//
+// The metadata file is written first, so the state file never exports values without their metadata.
+// Lines of the state file that are not exports are kept at its top.
+func rewriteState(root *Root, update stateUpdate) error {
+	filePath := StateFile(root)
+	at := now()
+
+	state, other, err := loadState(root)
+	if err != nil {
+		return err
+	}
+
+	// nothing is written if any variable can't be exported
+	for _, entry := range update.entries {
+		if !envVarPattern.MatchString(entry.Var) {
+			return fmt.Errorf("invalid environment variable name %q", entry.Var)
+		}
+	}
+
+	var changes []HistoryEntry
+
+	for _, target := range update.unset {
+		if entry, ok := state.Entry(target); ok {
+			changes = append(changes, HistoryEntry{
+				Var:         target,
+				Selector:    entry.Selector,
+				Old:         &entry.Value,
+				OldSelector: entry.Selector,
+				OldItem:     entry.Item,
+				OldVerbatim: entry.Verbatim,
+				Source:      update.source,
+			})
+		}
+		state.unset(target)
+	}
+
+	for _, entry := range update.entries {
+		if entry.Time.IsZero() {
+			entry.Time = at
+		}
+
+		change := HistoryEntry{Var: entry.Var, Selector: entry.Selector, New: &entry.Value, Source: entry.Source}
+		if old, ok := state.Entry(entry.Var); ok {
+			change.Old = &old.Value
+			change.OldSelector = old.Selector
+			change.OldItem = old.Item
+			change.OldVerbatim = old.Verbatim
+		}
+		if change.Old == nil || *change.Old != entry.Value || change.OldVerbatim != entry.Verbatim {
+			changes = append(changes, change)
+		}
+
+		state.set(entry)
+	}
+
+	metadata, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(root.Fs, MetadataFile(root), append(metadata, '\n'), 0600); err != nil {
+		return err
+	}
+
+	// the state file is a POSIX export script, which all supported shells evaluate
+	script, err := state.Script("bash")
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	for _, line := range other {
+		b.WriteString(line + "\n")
+	}
+	b.WriteString(script)
+
+	perm := os.FileMode(0600)
+	if info, err := root.Fs.Stat(filePath); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	if err := writeFileAtomic(root.Fs, filePath, []byte(b.String()), perm); err != nil {
+		return err
+	}
+
+	for _, entry := range update.entries {
+		slog.Debug("Wrote environment variable to file", "path", filePath, "var", entry.Var, "value", entry.Value, "source", entry.Source)
+	}
+	for _, target := range update.unset {
+		slog.Debug("Removed environment variable from file", "path", filePath, "var", target)
+	}
+
+	return appendHistory(root, at, update.undoes, changes)
+}
+
+// exportLine returns the line of the state file exporting the variable, with the value shell-quoted.
+// Invalid variable names are refused, as the shell would evaluate them as code.
+func exportLine(target string, value string) (string, error) {
+	if !envVarPattern.MatchString(target) {
+		return "", fmt.Errorf("invalid environment variable name %q", target)
+	}
+
+	return fmt.Sprintf("export %s=%s", target, shellQuote(value)), nil
+}
+
+// parseExportLine parses a line of the state file exporting a variable.
+//
+// Quoted values are unquoted, see shellQuote, and reported as quoted. Unquoted values, written by earlier versions
+// of sevp or by hand, are read verbatim. Lines with invalid variable names are not exports of the state.
+func parseExportLine(line string) (target string, value string, quoted bool, ok bool) {
+	assignment, ok := strings.CutPrefix(line, "export ")
+	if !ok {
+		return "", "", false, false
+	}
+
+	target, value, ok = strings.Cut(assignment, "=")
+	if !ok || !envVarPattern.MatchString(target) {
+		return "", "", false, false
+	}
+
+	if strings.HasPrefix(value, "'") {
+		if unquoted, ok := shellUnquote(value); ok {
+			return target, unquoted, true, true
+		}
+	}
+
+	return target, value, false, true
+}
+
+// shellQuote quotes the value for POSIX shells: it is wrapped in single quotes, and every single quote in it
+// is replaced by a closing quote, an escaped quote and an opening quote.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// shellUnquote reverses shellQuote. It reports false if the value is not quoted like shellQuote quotes it.
+func shellUnquote(value string) (string, bool) {
+	var b strings.Builder
+
+	for i := 0; i < len(value); {
+		switch {
+		case value[i] == '\'':
+			end := strings.IndexByte(value[i+1:], '\'')
+			if end < 0 {
+				return "", false
+			}
+			b.WriteString(value[i+1 : i+1+end])
+			i += end + 2
+		case strings.HasPrefix(value[i:], `\'`):
+			b.WriteByte('\'')
+			i += 2
+		default:
+			return "", false
+		}
+	}
+
+	return b.String(), true
+}
+
+// readLines reads a file of the filesystem line by line.
+func readLines(fs afero.Fs, filePath string) ([]string, error) {
+	file, err := fs.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	return lines, scanner.Err()
+}
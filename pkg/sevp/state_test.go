@@ -0,0 +1,330 @@
+package sevp
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Writing to a file should create the file if it doesn't exist
+func TestWriteToFile(t *testing.T) {
+	tempDir := t.TempDir()
+	tempFile := filepath.Join(tempDir, ".sevp")
+
+	// use the temporary directory as the user's home directory
+	root := NewRoot(afero.NewOsFs(), tempDir)
+
+	// test writing a new environment variable
+	err := WriteToFile(root, "test_value", "TEST_VAR")
+	assert.NoError(t, err, "expected no error writing to file")
+
+	// verify the file
+	content, err := os.ReadFile(tempFile)
+	assert.NoError(t, err, "expected no error reading file")
+	assert.Contains(t, string(content), "export TEST_VAR='test_value'", "file content should contain the environment variable")
+
+	// test updating an existing environment variable
+	err = WriteToFile(root, "new_value", "TEST_VAR")
+	assert.NoError(t, err, "expected no error writing to file")
+
+	// verify the updated file content
+	content, err = os.ReadFile(tempFile)
+	assert.NoError(t, err, "expected no error reading file")
+	assert.Contains(t, string(content), "export TEST_VAR='new_value'", "file content should contain the updated environment variable")
+
+	// test adding another environment variable
+	err = WriteToFile(root, "another_value", "ANOTHER_VAR")
+	assert.NoError(t, err, "expected no error writing to file")
+
+	// verify the file content
+	content, err = os.ReadFile(tempFile)
+	assert.NoError(t, err, "expected no error reading file")
+	assert.Contains(t, string(content), "export ANOTHER_VAR='another_value'", "file content should contain the new environment variable")
+
+	// verify the resulting file contains all environment variables
+	content, err = os.ReadFile(tempFile)
+	assert.NoError(t, err, "expected no error reading file")
+	assert.Contains(t, string(content), "export TEST_VAR='new_value'", "file content should contain the updated environment variable")
+	assert.Contains(t, string(content), "export ANOTHER_VAR='another_value'", "file content should contain the new environment variable")
+}
+
+// Reading the state file should return the exported variables
+func TestReadState(t *testing.T) {
+	root := newTestRoot()
+
+	// a missing state file results in an empty state
+	state, err := ReadState(root)
+	assert.NoError(t, err, "expected no error reading missing state file")
+	assert.Empty(t, state, "state should be empty")
+
+	err = WriteToFile(root, "test_value", "TEST_VAR")
+	assert.NoError(t, err, "expected no error writing to file")
+	err = WriteToFile(root, "another_value", "ANOTHER_VAR")
+	assert.NoError(t, err, "expected no error writing to file")
+
+	state, err = ReadState(root)
+	assert.NoError(t, err, "expected no error reading state file")
+	assert.Equal(t, map[string]string{"TEST_VAR": "test_value", "ANOTHER_VAR": "another_value"}, state)
+}
+
+// Writing several values should update the existing ones, keep other lines on top and export all in order
+func TestWriteValues(t *testing.T) {
+	root := newTestRoot()
+	writeTestFiles(t, root, map[string]string{StateFile(root): "export B=old\nalias k=kubectl\n"})
+
+	err := WriteValues(root, map[string]string{"C": "c", "B": "b", "A": "a"})
+	assert.NoError(t, err, "expected no error writing values")
+
+	content, err := afero.ReadFile(root.Fs, StateFile(root))
+	assert.NoError(t, err)
+	assert.Equal(t, "alias k=kubectl\nexport A='a'\nexport B='b'\nexport C='c'\n", string(content))
+}
+
+// The state should record how and when values were set, and migrate state files without metadata
+func TestLoadState(t *testing.T) {
+	at := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	defer func(original func() time.Time) { now = original }(now)
+	now = func() time.Time { return at }
+
+	root := newTestRoot()
+	writeTestFiles(t, root, map[string]string{StateFile(root): "export AWS_PROFILE=legacy\n"})
+
+	state, err := LoadState(root)
+	require.NoError(t, err)
+	require.Len(t, state.Entries, 1)
+	assert.Equal(t, SourceUnknown, state.Entries[0].Source, "values of earlier versions have no metadata")
+
+	err = WriteAssignments(root, SourcePicker, []Assignment{
+		{Selector: "kube", TargetVar: "KUBECONFIG", Item: "dev", Value: "/kube/dev.yaml"},
+	})
+	require.NoError(t, err)
+
+	state, err = LoadState(root)
+	require.NoError(t, err)
+	assert.Equal(t, []StateEntry{
+		{Var: "AWS_PROFILE", Value: "legacy", Source: SourceUnknown, Time: state.Entries[0].Time, Verbatim: true},
+		{Var: "KUBECONFIG", Value: "/kube/dev.yaml", Selector: "kube", Item: "dev", Source: SourcePicker, Time: at},
+	}, state.Entries)
+
+	exists, err := afero.Exists(root.Fs, MetadataFile(root))
+	require.NoError(t, err)
+	assert.True(t, exists, "the metadata file should be written")
+
+	// values changed by hand lose their metadata, as the state file is what shells evaluate
+	writeTestFiles(t, root, map[string]string{StateFile(root): "export AWS_PROFILE=legacy\nexport KUBECONFIG=/kube/prod.yaml\n"})
+
+	state, err = LoadState(root)
+	require.NoError(t, err)
+	entry, ok := state.Entry("KUBECONFIG")
+	require.True(t, ok)
+	assert.Equal(t, "/kube/prod.yaml", entry.Value)
+	assert.Equal(t, SourceUnknown, entry.Source)
+
+	script, err := state.Script("zsh")
+	require.NoError(t, err)
+	assert.Equal(t, "export AWS_PROFILE=legacy\nexport KUBECONFIG=/kube/prod.yaml\n", script)
+
+	_, err = state.Script("fish")
+	assert.Error(t, err, "expected error for unsupported shell")
+}
+
+// Unquoted values of earlier versions should be kept as the shell expands them until they are picked again
+func TestStateLegacyValues(t *testing.T) {
+	root := newTestRoot()
+	writeTestFiles(t, root, map[string]string{StateFile(root): "export KUBECONFIG=$HOME/.kube/a\nexport P=~/x\n"})
+
+	require.NoError(t, WriteAssignments(root, SourcePicker, []Assignment{{Selector: "aws", TargetVar: "AWS_PROFILE", Item: "dev", Value: "dev"}}))
+
+	content, err := afero.ReadFile(root.Fs, StateFile(root))
+	require.NoError(t, err)
+	assert.Equal(t, "export AWS_PROFILE='dev'\nexport KUBECONFIG=$HOME/.kube/a\nexport P=~/x\n", string(content), "legacy values are kept byte-for-byte")
+
+	envrc, err := Envrc(root)
+	require.NoError(t, err)
+	assert.Equal(t, string(content), envrc)
+
+	require.NoError(t, WriteAssignments(root, SourcePicker, []Assignment{{Selector: "kube", TargetVar: "KUBECONFIG", Item: "b", Value: "$HOME/.kube/b"}}))
+
+	content, err = afero.ReadFile(root.Fs, StateFile(root))
+	require.NoError(t, err)
+	assert.Equal(t, "export AWS_PROFILE='dev'\nexport KUBECONFIG='$HOME/.kube/b'\nexport P=~/x\n", string(content), "picked values are quoted")
+
+	_, err = Undo(root, nil)
+	require.NoError(t, err)
+
+	content, err = afero.ReadFile(root.Fs, StateFile(root))
+	require.NoError(t, err)
+	assert.Equal(t, "export AWS_PROFILE='dev'\nexport KUBECONFIG=$HOME/.kube/a\nexport P=~/x\n", string(content), "undo restores legacy values as written")
+}
+
+// Only the state file should follow the state file option, the files derived from it stay in the home directory
+func TestStateFileSidecars(t *testing.T) {
+	root := newTestRoot()
+	assert.Equal(t, "/home/test/.sevp.json", MetadataFile(root))
+	assert.Equal(t, "/home/test/.sevp.history", HistoryFile(root))
+	assert.Equal(t, "/home/test/.sevp.usage.json", UsageFile(root))
+
+	root.StatePath = "/home/test/repo/.envrc.local"
+	require.NoError(t, WriteAssignments(root, SourcePicker, []Assignment{{Selector: "kube", TargetVar: "KUBECONFIG", Item: "dev", Value: "dev"}}))
+	require.NoError(t, RecordUse(root, "kube", "dev"))
+
+	files, err := afero.ReadDir(root.Fs, "/home/test/repo")
+	require.NoError(t, err)
+	require.Len(t, files, 1, "only the state file is written to the project")
+	assert.Equal(t, ".envrc.local", files[0].Name())
+
+	for _, path := range []string{MetadataFile(root), HistoryFile(root), UsageFile(root)} {
+		assert.True(t, strings.HasPrefix(path, "/home/test/.sevp-state/.envrc.local-"), path)
+		exists, err := afero.Exists(root.Fs, path)
+		require.NoError(t, err)
+		assert.True(t, exists, path)
+	}
+
+	other := *root
+	other.StatePath = "/home/test/other/.envrc.local"
+	assert.NotEqual(t, MetadataFile(root), MetadataFile(&other), "the files are keyed by the path of the state file")
+}
+
+// Variable names with shell syntax should never be written to the state file
+func TestStateInvalidNames(t *testing.T) {
+	root := newTestRoot()
+	require.NoError(t, WriteValues(root, map[string]string{"AWS_PROFILE": "dev"}))
+
+	err := WriteToFile(root, "v", "A=1; touch /tmp/pwned; B")
+	assert.ErrorContains(t, err, `invalid environment variable name "A=1; touch /tmp/pwned; B"`)
+
+	content, err := afero.ReadFile(root.Fs, StateFile(root))
+	require.NoError(t, err)
+	assert.Equal(t, "export AWS_PROFILE='dev'\n", string(content), "nothing is written")
+
+	_, err = (&State{Entries: []StateEntry{{Var: "A;B", Value: "v"}}}).Script("bash")
+	assert.ErrorContains(t, err, `invalid environment variable name "A;B"`)
+}
+
+// Values with shell syntax should be quoted in the state file and read back as literal strings
+func TestStateQuoting(t *testing.T) {
+	root := newTestRoot()
+
+	values := map[string]string{
+		"SEMICOLON": "a;echo PWNED",
+		"COMMAND":   "$(touch /tmp/PWNED)",
+		"BACKTICK":  "`id`",
+		"QUOTE":     "it's'; echo PWNED; '",
+		"EMPTY":     "",
+	}
+	require.NoError(t, WriteValues(root, values))
+
+	content, err := afero.ReadFile(root.Fs, StateFile(root))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `export SEMICOLON='a;echo PWNED'`)
+	assert.Contains(t, string(content), `export QUOTE='it'\''s'\''; echo PWNED; '\'''`)
+
+	// without the metadata, the values are read from the state file alone
+	require.NoError(t, root.Fs.Remove(MetadataFile(root)))
+
+	state, err := ReadState(root)
+	require.NoError(t, err)
+	assert.Equal(t, values, state)
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	// the shell exports the very same strings
+	for target, value := range values {
+		out, err := exec.Command("sh", "-c", string(content)+`printf %s "$`+target+`"`).Output()
+		require.NoError(t, err)
+		assert.Equal(t, value, string(out), target)
+	}
+}
+
+// Concurrent writers should never lose each other's updates or leave temporary files behind
+func TestWriteToFileConcurrent(t *testing.T) {
+	roots := map[string]*Root{
+		"memory": newTestRoot(),
+		"os":     NewRoot(afero.NewOsFs(), t.TempDir()),
+	}
+
+	for name, root := range roots {
+		t.Run(name, func(t *testing.T) {
+			const writers, writes = 16, 25
+
+			var wg sync.WaitGroup
+			for w := 0; w < writers; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					for i := 0; i < writes; i++ {
+						// separate roots, just like separate sevp processes
+						writerRoot := &Root{Fs: root.Fs, Home: root.Home, Workdir: root.Workdir}
+						assert.NoError(t, WriteToFile(writerRoot, fmt.Sprint(i), fmt.Sprintf("VAR_%d", w)))
+					}
+				}(w)
+			}
+			wg.Wait()
+
+			state, err := ReadState(root)
+			require.NoError(t, err)
+			assert.Len(t, state, writers, "no update should be lost")
+			for w := 0; w < writers; w++ {
+				assert.Equal(t, fmt.Sprint(writes-1), state[fmt.Sprintf("VAR_%d", w)])
+			}
+
+			files, err := afero.ReadDir(root.Fs, root.Home)
+			require.NoError(t, err)
+			for _, file := range files {
+				assert.NotContains(t, file.Name(), ".tmp-", "temporary files should be removed")
+			}
+		})
+	}
+}
+
+// Concurrent sevp processes should never lose each other's updates
+func TestWriteToFileConcurrentProcesses(t *testing.T) {
+	const writers, writes = 8, 25
+
+	// the test binary runs itself as the writer processes
+	if writer := os.Getenv("SEVP_TEST_WRITER"); writer != "" {
+		root := NewRoot(afero.NewOsFs(), os.Getenv("SEVP_TEST_HOME"))
+		for i := 0; i < writes; i++ {
+			require.NoError(t, WriteToFile(root, fmt.Sprint(i), "VAR_"+writer))
+		}
+		return
+	}
+
+	if runtime.GOOS == "windows" {
+		t.Skip("advisory file locks are not supported")
+	}
+
+	home := t.TempDir()
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			cmd := exec.Command(os.Args[0], "-test.run=^TestWriteToFileConcurrentProcesses$")
+			cmd.Env = append(os.Environ(), fmt.Sprintf("SEVP_TEST_WRITER=%d", w), "SEVP_TEST_HOME="+home)
+			output, err := cmd.CombinedOutput()
+			assert.NoError(t, err, "writer %d failed: %s", w, output)
+		}(w)
+	}
+	wg.Wait()
+
+	state, err := ReadState(NewRoot(afero.NewOsFs(), home))
+	require.NoError(t, err)
+	assert.Len(t, state, writers, "no update should be lost")
+	for w := 0; w < writers; w++ {
+		assert.Equal(t, fmt.Sprint(writes-1), state[fmt.Sprintf("VAR_%d", w)])
+	}
+}
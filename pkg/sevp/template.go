@@ -0,0 +1,186 @@
+package sevp
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// ValueRenderer is implemented by selectors that derive the value written to the state file from the picked item.
+type ValueRenderer interface {
+	RenderValue(item string) (string, error)
+}
+
+// MetadataProvider is implemented by selectors that describe their items, e.g. the region of an AWS profile.
+type MetadataProvider interface {
+	Metadata(item string) (map[string]string, error)
+}
+
+// ItemMetadata returns the metadata of the item of the selector, or nil if the selector has none.
+func ItemMetadata(s Selector, item string) (map[string]string, error) {
+	if provider, ok := s.(MetadataProvider); ok {
+		return provider.Metadata(item)
+	}
+
+	return nil, nil
+}
+
+// TemplateData is the data value templates are rendered with.
+type TemplateData struct {
+	// Value is the picked item.
+	Value string
+
+	// Selector is the name of the selector.
+	Selector string
+
+	// TargetVar is the environment variable the value is written to.
+	TargetVar string
+
+	// Env holds the environment variables.
+	Env map[string]string
+
+	// Meta holds the metadata of the picked item.
+	Meta map[string]string
+}
+
+// RenderValue returns the value to write for the item picked from the selector.
+//
+// Selectors implementing ValueRenderer render the value, for all others the item is the value.
+func RenderValue(s Selector, item string) (string, error) {
+	if renderer, ok := s.(ValueRenderer); ok {
+		return renderer.RenderValue(item)
+	}
+
+	return item, nil
+}
+
+// RenderValue renders the value template of the selector for the picked item.
+//
+// Without a value template, the item is the value. Referencing missing keys of Env or Meta is an error.
+func (s *ConfigSelector) RenderValue(item string) (string, error) {
+	return s.renderValue(item, s.TargetVar)
+}
+
+// renderValue renders the value template of the selector for the picked item written to the target variable.
+func (s *ConfigSelector) renderValue(item string, targetVar string) (string, error) {
+	if s.ValueTemplate == "" {
+		return item, nil
+	}
+
+	tmpl, err := parseValueTemplate(s.ValueTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	meta := s.meta(item)
+	if meta == nil {
+		meta = map[string]string{}
+	}
+
+	var b strings.Builder
+	err = tmpl.Execute(&b, TemplateData{
+		Value:     item,
+		Selector:  s.Name,
+		TargetVar: targetVar,
+		Env:       environ(),
+		Meta:      meta,
+	})
+	if err != nil {
+		return "", fmt.Errorf("rendering value_template for %q: %w", item, err)
+	}
+
+	return b.String(), nil
+}
+
+// Metadata returns the metadata of the item in the meta table of the selector.
+func (s *ConfigSelector) Metadata(item string) (map[string]string, error) {
+	return s.meta(item), nil
+}
+
+// meta returns the metadata of the item in the meta table of the selector, or nil if it has none.
+func (s *ConfigSelector) meta(item string) map[string]string {
+	meta, ok := s.Meta[item]
+	if !ok {
+		// keys are lowercased when the config is read
+		meta = s.Meta[strings.ToLower(item)]
+	}
+
+	return meta
+}
+
+// parseValueTemplate parses a value template.
+func parseValueTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("value_template").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value_template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// environ returns the environment variables as a map.
+func environ() map[string]string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			env[key] = value
+		}
+	}
+
+	return env
+}
+
+// templatedSelector is an external config selector rendering values with the value template of its config
+// and describing items with its meta table.
+type templatedSelector struct {
+	Selector
+
+	// config is the config selector holding the value template.
+	config *ConfigSelector
+
+	// targetVar is the target variable of the external config, once it was read.
+	targetVar string
+}
+
+// Read reads the external config, remembering its target variable for rendering.
+func (s *templatedSelector) Read() (string, []string, error) {
+	targetVar, values, err := s.Selector.Read()
+	s.targetVar = targetVar
+
+	return targetVar, values, err
+}
+
+// RenderValue renders the value template of the config for the picked item.
+func (s *templatedSelector) RenderValue(item string) (string, error) {
+	if s.targetVar == "" {
+		if _, _, err := s.Read(); err != nil {
+			return "", err
+		}
+	}
+
+	return s.config.renderValue(item, s.targetVar)
+}
+
+// Metadata returns the metadata of the item from the external config, overridden by the meta table of the config.
+func (s *templatedSelector) Metadata(item string) (map[string]string, error) {
+	metadata, err := ItemMetadata(s.Selector, item)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := s.config.meta(item)
+	if len(meta) == 0 {
+		return metadata, nil
+	}
+
+	merged := make(map[string]string, len(metadata)+len(meta))
+	for key, value := range metadata {
+		merged[key] = value
+	}
+	for key, value := range meta {
+		merged[key] = value
+	}
+
+	return merged, nil
+}
@@ -0,0 +1,140 @@
+package sevp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Rendering values should use the value template with the item, metadata and environment
+func TestRenderValue(t *testing.T) {
+	t.Setenv("SEVP_TEST_HOME", "/home/alice")
+
+	cfg, err := ReadConfig(strings.NewReader(`
+[kube]
+target_var = "KUBECONFIG"
+possible_values = ["staging", "prod"]
+value_template = "{{ .Env.SEVP_TEST_HOME }}/.kube/{{ .Value }}.yaml"
+
+[gcp]
+target_var = "CLOUDSDK_CORE_PROJECT"
+possible_values = ["Acme", "globex"]
+value_template = "{{ .Meta.project }}"
+
+[gcp.meta.Acme]
+project = "acme-prod-1234"
+
+[plain]
+target_var = "PLAIN"
+possible_values = ["a"]
+`))
+	require.NoError(t, err)
+
+	kube, err := cfg.FromConfig("kube")
+	require.NoError(t, err)
+	value, err := RenderValue(kube, "staging")
+	require.NoError(t, err)
+	assert.Equal(t, "/home/alice/.kube/staging.yaml", value)
+
+	gcp, err := cfg.FromConfig("gcp")
+	require.NoError(t, err)
+	value, err = gcp.RenderValue("Acme")
+	require.NoError(t, err)
+	assert.Equal(t, "acme-prod-1234", value)
+
+	_, err = gcp.RenderValue("globex")
+	assert.ErrorContains(t, err, `rendering value_template for "globex"`)
+
+	plain, err := cfg.FromConfig("plain")
+	require.NoError(t, err)
+	value, err = RenderValue(plain, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "a", value)
+
+	diagnostics := cfg.Validate()
+	require.Len(t, diagnostics, 1)
+	assert.Equal(t, "gcp.value_template", diagnostics[0].Key)
+	assert.Contains(t, diagnostics[0].Message, "globex")
+}
+
+// External config selectors should render values with the value template of their config
+func TestRenderValueExternalSelector(t *testing.T) {
+	root := newTestRoot()
+	writeTestFiles(t, root, map[string]string{
+		"/home/test/sevp.toml": `
+[aws]
+external_config = true
+value_template = "{{ .TargetVar }}={{ .Value }}"
+`,
+		"/home/test/.aws/config": "[profile dev]\n",
+	})
+
+	cfg, err := LoadConfig(root, "/home/test/sevp.toml")
+	require.NoError(t, err)
+
+	selector, err := cfg.GetSelector(root, []string{"aws"})
+	require.NoError(t, err)
+
+	value, err := RenderValue(selector, "dev")
+	require.NoError(t, err)
+	assert.Equal(t, "AWS_PROFILE=dev", value)
+
+	require.NoError(t, afero.WriteFile(root.Fs, "/home/test/sevp.toml", []byte("[aws]\nexternal_config = true\nvalue_template = \"{{ .Value\"\n"), 0600))
+	cfg, err = LoadConfig(root, "/home/test/sevp.toml")
+	require.NoError(t, err)
+
+	diagnostics := cfg.Validate()
+	require.Len(t, diagnostics, 1)
+	assert.Equal(t, "/home/test/sevp.toml:3: aws.value_template: invalid value_template: template: value_template:1: unclosed action", diagnostics[0].String())
+}
+
+// Item metadata should come from the external config, overridden by the meta table of the config
+func TestItemMetadata(t *testing.T) {
+	root := newTestRoot()
+	writeTestFiles(t, root, map[string]string{
+		"/home/test/sevp.toml": `
+[aws]
+external_config = true
+
+[aws.meta.dev]
+owner = "platform"
+region = "us-east-1"
+
+[plain]
+target_var = "PLAIN"
+possible_values = ["a", "b"]
+
+[plain.meta.a]
+note = "first"
+`,
+		"/home/test/.aws/config": "[profile dev]\nregion = eu-west-1\nsso_account_id = 123456789012\n[profile prod]\n",
+	})
+
+	cfg, err := LoadConfig(root, "/home/test/sevp.toml")
+	require.NoError(t, err)
+
+	aws, err := cfg.GetSelector(root, []string{"aws"})
+	require.NoError(t, err)
+
+	metadata, err := ItemMetadata(aws, "dev")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"owner": "platform", "region": "us-east-1", "sso_account_id": "123456789012"}, metadata)
+
+	metadata, err = ItemMetadata(aws, "prod")
+	require.NoError(t, err)
+	assert.Empty(t, metadata)
+
+	plain, err := cfg.GetSelector(root, []string{"plain"})
+	require.NoError(t, err)
+
+	metadata, err = ItemMetadata(plain, "a")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"note": "first"}, metadata)
+
+	metadata, err = ItemMetadata(plain, "b")
+	require.NoError(t, err)
+	assert.Nil(t, metadata)
+}
@@ -0,0 +1,96 @@
+package sevp
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// Built-in themes, see Theme.
+const (
+	ThemeDark         = "dark"
+	ThemeLight        = "light"
+	ThemeHighContrast = "high-contrast"
+)
+
+// ThemePresets are the names of the built-in themes, the first one is the default.
+var ThemePresets = []string{ThemeDark, ThemeLight, ThemeHighContrast}
+
+// colourPattern matches the colours themes accept: hex colours like #B198E5 or #fff, and ANSI colour numbers.
+var colourPattern = regexp.MustCompile(`^(#[0-9a-fA-F]{6}|#[0-9a-fA-F]{3}|[0-9]{1,3})$`)
+
+// Theme configures the colours of the TUI and the CLI output, set in the [theme] table of the config.
+//
+// A theme starts from a built-in preset, whose colours can be overridden one by one.
+// Themes are merged key by key across all layers, just like options.
+type Theme struct {
+	// Preset is the built-in theme the colours are based on, see ThemePresets. Empty means ThemeDark.
+	Preset string `mapstructure:"preset" description:"The built-in theme the colours are based on: dark, light or high-contrast. Defaults to dark."`
+
+	// Accent is the colour of target variables, titles and borders.
+	Accent string `mapstructure:"accent" description:"Colour of target variables, titles and borders, as hex colour (#B198E5) or ANSI colour number."`
+
+	// Highlight is the colour of the selected item and of picked values.
+	Highlight string `mapstructure:"highlight" description:"Colour of the selected item and of picked values, as hex colour (#3CCE92) or ANSI colour number."`
+
+	// Error is the colour of errors.
+	Error string `mapstructure:"error" description:"Colour of errors, as hex colour (#F25D94) or ANSI colour number."`
+
+	// Text is the colour of the search prompt.
+	Text string `mapstructure:"text" description:"Colour of the search prompt, as hex colour (#FFFFFF) or ANSI colour number."`
+}
+
+// decodeTheme decodes the raw values of the theme table, rejecting unknown keys, presets and colours.
+func decodeTheme(raw map[string]any) (Theme, error) {
+	var theme Theme
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		ErrorUnused: true,
+		Result:      &theme,
+	})
+	if err != nil {
+		return theme, err
+	}
+
+	if err := decoder.Decode(raw); err != nil {
+		return theme, fmt.Errorf("invalid theme: %w", err)
+	}
+
+	if theme.Preset != "" && !slices.Contains(ThemePresets, theme.Preset) {
+		return theme, fmt.Errorf("invalid theme: unknown preset %q (known presets: %s)", theme.Preset, strings.Join(ThemePresets, ", "))
+	}
+
+	colours := map[string]string{"accent": theme.Accent, "highlight": theme.Highlight, "error": theme.Error, "text": theme.Text}
+	for _, key := range sortedKeys(colours) {
+		if colour := colours[key]; colour != "" && !colourPattern.MatchString(colour) {
+			return theme, fmt.Errorf("invalid theme: %s: invalid colour %q, expected a hex colour like #B198E5 or an ANSI colour number", key, colour)
+		}
+	}
+
+	return theme, nil
+}
+
+// mergeTheme merges the raw theme of another config into the theme of the config.
+//
+// Keys already set are only replaced if override is set.
+func (c *Config) mergeTheme(raw map[string]any, override bool) {
+	if len(raw) == 0 {
+		return
+	}
+
+	if c.rawTheme == nil {
+		c.rawTheme = make(map[string]any)
+	}
+	for key, value := range raw {
+		if _, ok := c.rawTheme[key]; ok && !override {
+			continue
+		}
+		c.rawTheme[key] = value
+	}
+
+	// the themes of every file were decoded successfully when they were read, so the merged one decodes as well
+	c.Theme, _ = decodeTheme(c.rawTheme)
+}
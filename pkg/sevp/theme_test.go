@@ -0,0 +1,72 @@
+package sevp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The theme table should be decoded, rejecting unknown keys, presets and colours
+func TestReadTheme(t *testing.T) {
+	cfg, err := ReadConfig(strings.NewReader(`
+[theme]
+preset = "light"
+accent = "#6A4FB3"
+error = "196"
+
+[kube]
+target_var = "KUBECONFIG"
+possible_values = ["dev"]
+`))
+	require.NoError(t, err)
+	assert.Equal(t, Theme{Preset: ThemeLight, Accent: "#6A4FB3", Error: "196"}, cfg.Theme)
+	assert.Equal(t, []string{"kube"}, cfg.Names(), "theme is not a selector")
+
+	tests := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{"unknown key", "[theme]\ncolour = \"#fff\"\n", "invalid theme"},
+		{"unknown preset", "[theme]\npreset = \"solarized\"\n", `unknown preset "solarized" (known presets: dark, light, high-contrast)`},
+		{"invalid colour", "[theme]\nhighlight = \"green\"\n", `highlight: invalid colour "green"`},
+		{"not a table", "theme = \"dark\"\n", "theme must be a table"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadConfig(strings.NewReader(tt.config))
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+// Themes should be merged key by key across layers
+func TestThemeLayers(t *testing.T) {
+	root := newTestRoot()
+	root.Workdir = "/home/test/repo"
+	t.Setenv("SEVP_SYSTEM_CONFIG", "/etc/sevp/sevp.toml")
+	t.Setenv("SEVP_TEAM_CONFIG", "")
+
+	writeTestFiles(t, root, map[string]string{
+		"/etc/sevp/sevp.toml": `
+[theme]
+preset = "high-contrast"
+accent = "#00FFFF"
+`,
+		"/home/test/.config/sevp.toml": `
+[theme]
+accent = "#B198E5"
+
+[kube]
+target_var = "KUBECONFIG"
+possible_values = ["dev"]
+`,
+	})
+
+	cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	require.NoError(t, err)
+	assert.Equal(t, Theme{Preset: ThemeHighContrast, Accent: "#B198E5"}, cfg.Theme)
+}
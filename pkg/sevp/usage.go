@@ -0,0 +1,227 @@
+package sevp
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// RecentItems is the number of most frecently used items ranked as RankRecent.
+const RecentItems = 5
+
+// Rank is the section of the picker an item is shown in, see Usage.Rank.
+type Rank int
+
+const (
+	// RankPinned items are pinned by the user and shown first.
+	RankPinned Rank = iota
+
+	// RankRecent items are the most frecently used ones, shown after the pinned ones.
+	RankRecent
+
+	// RankOther items are all other items, shown in the order of their selector.
+	RankOther
+)
+
+// RankedItem is an item of a selector and its section in the picker.
+type RankedItem struct {
+	Value string
+	Rank  Rank
+}
+
+// ItemUsage is how often and when an item was picked.
+type ItemUsage struct {
+	// Count is how often the item was picked.
+	Count int `json:"count"`
+
+	// Last is when the item was last picked.
+	Last time.Time `json:"last"`
+}
+
+// frecency scores the usage by how often and how recently the item was picked,
+// weighting picks of the last hours higher than the ones of past months.
+func (u ItemUsage) frecency(at time.Time) int {
+	age := at.Sub(u.Last)
+
+	weight := 10
+	switch {
+	case age < 4*time.Hour:
+		weight = 100
+	case age < 24*time.Hour:
+		weight = 70
+	case age < 7*24*time.Hour:
+		weight = 50
+	case age < 30*24*time.Hour:
+		weight = 30
+	}
+
+	return u.Count * weight
+}
+
+// SelectorUsage is the usage of the items of a selector.
+type SelectorUsage struct {
+	// Items maps the picked items to their usage.
+	Items map[string]*ItemUsage `json:"items,omitempty"`
+
+	// Pinned are the pinned items, in the order they were pinned in.
+	Pinned []string `json:"pinned,omitempty"`
+}
+
+// Usage is the usage of the items of all selectors, used to rank the items in the picker.
+//
+// It is stored as JSON next to the state file, see UsageFile.
+type Usage struct {
+	// Selectors maps the names of selectors to their usage.
+	Selectors map[string]*SelectorUsage `json:"selectors"`
+}
+
+// Rank returns the items of the selector ranked by their usage: the pinned items first, in the order they were
+// pinned in, then the RecentItems most frecently used items and all other items in their original order.
+func (u *Usage) Rank(selector string, items []string) []RankedItem {
+	usage := u.Selectors[selector]
+	if usage == nil {
+		usage = &SelectorUsage{}
+	}
+
+	ranked := make([]RankedItem, 0, len(items))
+	done := make(map[string]bool)
+
+	for _, item := range usage.Pinned {
+		if slices.Contains(items, item) && !done[item] {
+			ranked = append(ranked, RankedItem{Value: item, Rank: RankPinned})
+			done[item] = true
+		}
+	}
+
+	var recent []string
+	for _, item := range items {
+		if _, used := usage.Items[item]; used && !done[item] {
+			recent = append(recent, item)
+		}
+	}
+	at := now()
+	sort.SliceStable(recent, func(i, j int) bool {
+		return usage.Items[recent[i]].frecency(at) > usage.Items[recent[j]].frecency(at)
+	})
+	if len(recent) > RecentItems {
+		recent = recent[:RecentItems]
+	}
+	for _, item := range recent {
+		ranked = append(ranked, RankedItem{Value: item, Rank: RankRecent})
+		done[item] = true
+	}
+
+	for _, item := range items {
+		if !done[item] {
+			ranked = append(ranked, RankedItem{Value: item, Rank: RankOther})
+			done[item] = true
+		}
+	}
+
+	return ranked
+}
+
+// selector returns the usage of the selector, adding it if it has none yet.
+func (u *Usage) selector(name string) *SelectorUsage {
+	if u.Selectors == nil {
+		u.Selectors = make(map[string]*SelectorUsage)
+	}
+	if u.Selectors[name] == nil {
+		u.Selectors[name] = &SelectorUsage{}
+	}
+
+	return u.Selectors[name]
+}
+
+// UsageFile returns the path of the usage file of the state file of the root.
+func UsageFile(root *Root) string {
+	return sidecarPath(root) + ".usage.json"
+}
+
+// LoadUsage reads the usage of the root. A missing or invalid usage file results in no usage.
+func LoadUsage(root *Root) (*Usage, error) {
+	path := UsageFile(root)
+
+	data, err := afero.ReadFile(root.Fs, path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &Usage{}, nil
+		}
+		return nil, err
+	}
+
+	var usage Usage
+	if err := json.Unmarshal(data, &usage); err != nil {
+		// usage only affects the order of the items, so it must not block picking
+		slog.Warn("Ignoring invalid usage file", "path", path, "err", err)
+		return &Usage{}, nil
+	}
+
+	return &usage, nil
+}
+
+// RecordUse records that the item of the selector was picked.
+func RecordUse(root *Root, selector string, item string) error {
+	return updateUsage(root, func(usage *Usage) {
+		s := usage.selector(selector)
+		if s.Items == nil {
+			s.Items = make(map[string]*ItemUsage)
+		}
+		if s.Items[item] == nil {
+			s.Items[item] = &ItemUsage{}
+		}
+
+		s.Items[item].Count++
+		s.Items[item].Last = now()
+	})
+}
+
+// TogglePin pins the item of the selector, or unpins it if it is pinned, and reports whether it is pinned now.
+func TogglePin(root *Root, selector string, item string) (bool, error) {
+	var pinned bool
+
+	err := updateUsage(root, func(usage *Usage) {
+		s := usage.selector(selector)
+
+		if i := slices.Index(s.Pinned, item); i >= 0 {
+			s.Pinned = slices.Delete(s.Pinned, i, i+1)
+			return
+		}
+
+		s.Pinned = append(s.Pinned, item)
+		pinned = true
+	})
+
+	return pinned, err
+}
+
+// updateUsage applies the update to the usage of the root, serialised with a lock.
+func updateUsage(root *Root, update func(usage *Usage)) error {
+	path := UsageFile(root)
+
+	unlock, err := lockFile(root.Fs, path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	usage, err := LoadUsage(root)
+	if err != nil {
+		return err
+	}
+
+	update(usage)
+
+	data, err := json.MarshalIndent(usage, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(root.Fs, path, append(data, '\n'), 0600)
+}
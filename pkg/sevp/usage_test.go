@@ -0,0 +1,62 @@
+package sevp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Items should be ranked pinned first, then by frecency, then in their original order
+func TestUsageRank(t *testing.T) {
+	useTestClock(t)
+	root := newTestRoot()
+	items := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+
+	usage, err := LoadUsage(root)
+	require.NoError(t, err)
+	for _, ranked := range usage.Rank("aws", items) {
+		assert.Equal(t, RankOther, ranked.Rank, "without usage all items keep their order")
+	}
+
+	for _, item := range []string{"c", "c", "h", "b", "d", "e", "f", "g"} {
+		require.NoError(t, RecordUse(root, "aws", item))
+	}
+	require.NoError(t, RecordUse(root, "gcp", "a"))
+
+	pinned, err := TogglePin(root, "aws", "e")
+	require.NoError(t, err)
+	assert.True(t, pinned)
+
+	usage, err = LoadUsage(root)
+	require.NoError(t, err)
+	assert.Equal(t, []RankedItem{
+		{Value: "e", Rank: RankPinned},
+		{Value: "c", Rank: RankRecent},
+		{Value: "b", Rank: RankRecent},
+		{Value: "d", Rank: RankRecent},
+		{Value: "f", Rank: RankRecent},
+		{Value: "g", Rank: RankRecent},
+		{Value: "a", Rank: RankOther},
+		{Value: "h", Rank: RankOther},
+	}, usage.Rank("aws", items), "only the most frecently used items are recent")
+
+	pinned, err = TogglePin(root, "aws", "e")
+	require.NoError(t, err)
+	assert.False(t, pinned)
+
+	usage, err = LoadUsage(root)
+	require.NoError(t, err)
+	assert.Empty(t, usage.Selectors["aws"].Pinned)
+}
+
+// Recent picks should outweigh frequent picks of long ago
+func TestFrecency(t *testing.T) {
+	at := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	often := ItemUsage{Count: 5, Last: at.Add(-60 * 24 * time.Hour)}
+	recently := ItemUsage{Count: 1, Last: at.Add(-time.Hour)}
+
+	assert.Greater(t, recently.frecency(at), often.frecency(at))
+}
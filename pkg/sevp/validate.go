@@ -0,0 +1,348 @@
+package sevp
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2/unstable"
+)
+
+// envVarPattern matches valid environment variable names.
+var envVarPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// bareVarPattern matches shell variables referenced without braces, which the expander keeps literally.
+var bareVarPattern = regexp.MustCompile(`\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// Diagnostic is a problem found while validating the config.
+type Diagnostic struct {
+	// File is the config file the problem was found in, or empty if unknown.
+	File string
+
+	// Line is the line of the problem in the file, or 0 if unknown.
+	Line int
+
+	// Key is the dotted path of the offending key, e.g. "aws.target_var".
+	Key string
+
+	// Message describes the problem.
+	Message string
+}
+
+// String formats the diagnostic as "file:line: key: message".
+func (d Diagnostic) String() string {
+	var b strings.Builder
+
+	if d.File != "" {
+		b.WriteString(d.File)
+		if d.Line > 0 {
+			fmt.Fprintf(&b, ":%d", d.Line)
+		}
+		b.WriteString(": ")
+	}
+
+	if d.Key != "" {
+		b.WriteString(d.Key + ": ")
+	}
+
+	b.WriteString(d.Message)
+	return b.String()
+}
+
+// Validate checks every section of the config and returns all problems found,
+// ordered by the layering order of the files and their lines.
+//
+// Unlike FromConfig, which only checks the requested selector once it is used,
+// Validate checks the whole config including selectors that are never used.
+func (c *Config) Validate() []Diagnostic {
+	diagnostics := slices.Clone(c.diagnostics)
+
+	for _, name := range c.Names() {
+		sec := c.sections[name]
+
+		s, err := sec.decode(name)
+		if err != nil {
+			// type errors are already reported for the file they occur in
+			continue
+		}
+
+		if err := checkSelector(s); err != nil {
+			diagnostics = append(diagnostics, c.sectionDiagnostic(name, err.Error()))
+		}
+
+		diagnostics = append(diagnostics, c.checkExpansion(name, s)...)
+		diagnostics = append(diagnostics, c.checkValueTemplate(name, s)...)
+		diagnostics = append(diagnostics, c.checkDangerous(name, s)...)
+
+		if s.ReadExternalConfig && !slices.Contains(Providers(), name) {
+			diagnostics = append(diagnostics, c.sectionDiagnostic(name, fmt.Sprintf(
+				"external_config is set, but there is no provider named %q (available: %s)",
+				name, strings.Join(Providers(), ", "),
+			)))
+		}
+	}
+
+	diagnostics = append(diagnostics, c.checkSets()...)
+	diagnostics = append(diagnostics, c.checkValues()...)
+
+	if _, ok := c.sections[c.Default]; c.Default != "" && !ok {
+		diagnostics = append(diagnostics, Diagnostic{
+			File:    c.defaultSource.Path,
+			Line:    c.defaultLine,
+			Key:     "default",
+			Message: fmt.Sprintf("default selector %q is not defined", c.Default),
+		})
+	}
+
+	// order the diagnostics by file and line
+	fileOrder := make(map[string]int)
+	for i, file := range c.Files {
+		if _, ok := fileOrder[file.Path]; !ok {
+			fileOrder[file.Path] = i
+		}
+	}
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if fi, fj := fileOrder[diagnostics[i].File], fileOrder[diagnostics[j].File]; fi != fj {
+			return fi < fj
+		}
+		return diagnostics[i].Line < diagnostics[j].Line
+	})
+
+	return diagnostics
+}
+
+// checkExpansion checks the references in the values of the selector without running any commands.
+func (c *Config) checkExpansion(name string, s *ConfigSelector) []Diagnostic {
+	var diagnostics []Diagnostic
+	e := c.expander(name, true)
+
+	targetVar, err := e.expandValue("target_var", s.TargetVar)
+	switch {
+	case err != nil:
+		diagnostics = append(diagnostics, c.keyDiagnostic(name, "target_var", err.Error()))
+	case targetVar == "" || strings.Contains(targetVar, "$("):
+		// empty target variables are reported elsewhere, command output is only known at runtime
+	case targetVar != s.TargetVar && !envVarPattern.MatchString(targetVar):
+		diagnostics = append(diagnostics, c.keyDiagnostic(name, "target_var", fmt.Sprintf(
+			"%q (expanded from %q) is not a valid environment variable name", targetVar, s.TargetVar)))
+	case !envVarPattern.MatchString(targetVar):
+		diagnostics = append(diagnostics, c.keyDiagnostic(name, "target_var", fmt.Sprintf(
+			"%q is not a valid environment variable name", targetVar)))
+	}
+
+	for _, value := range s.PossibleValues {
+		if _, err := e.expandValue("possible_values", value); err != nil {
+			diagnostics = append(diagnostics, c.keyDiagnostic(name, "possible_values", err.Error()))
+		}
+		if hint := shellSyntax(value); hint != "" {
+			diagnostics = append(diagnostics, c.keyDiagnostic(name, "possible_values", fmt.Sprintf(
+				"%q is exported literally, the shell doesn't expand values; use %s", value, hint)))
+		}
+	}
+
+	return diagnostics
+}
+
+// shellSyntax returns what to use instead of the shell expansions in the value, which are exported literally:
+// bare variables like $HOME and a leading ~. It returns an empty string if the value has none.
+func shellSyntax(value string) string {
+	if value == "~" || strings.HasPrefix(value, "~/") {
+		return "${HOME}"
+	}
+
+	// escaped dollars are literal
+	if match := bareVarPattern.FindStringSubmatch(strings.ReplaceAll(value, "$$", "")); match != nil {
+		return "${" + match[1] + "}"
+	}
+
+	return ""
+}
+
+// checkValueTemplate checks that the value template of the selector renders for all of its possible values.
+func (c *Config) checkValueTemplate(name string, s *ConfigSelector) []Diagnostic {
+	if s.ValueTemplate == "" {
+		return nil
+	}
+
+	if _, err := parseValueTemplate(s.ValueTemplate); err != nil {
+		return []Diagnostic{c.keyDiagnostic(name, "value_template", err.Error())}
+	}
+
+	// the values of external configs are only known at runtime
+	if s.ReadExternalConfig {
+		return nil
+	}
+
+	var diagnostics []Diagnostic
+	e := c.expander(name, true)
+
+	for _, value := range s.PossibleValues {
+		// values with unresolved references are reported by checkExpansion
+		expanded, err := e.expandValue("possible_values", value)
+		if err != nil {
+			continue
+		}
+
+		if _, err := s.RenderValue(expanded); err != nil {
+			diagnostics = append(diagnostics, c.keyDiagnostic(name, "value_template", err.Error()))
+		}
+	}
+
+	return diagnostics
+}
+
+// sectionDiagnostic creates a diagnostic located at the definition of the named section.
+func (c *Config) sectionDiagnostic(name string, message string) Diagnostic {
+	sec := c.sections[name]
+
+	d := Diagnostic{Line: sec.line, Key: name, Message: message}
+	if len(sec.sources) > 0 {
+		d.File = sec.sources[0].Path
+	}
+
+	return d
+}
+
+// keyDiagnostic creates a diagnostic located where the key of the named section was last set.
+func (c *Config) keyDiagnostic(name string, key string, message string) Diagnostic {
+	d := c.sectionDiagnostic(name, message)
+	d.Key = name + "." + key
+
+	if loc, ok := c.sections[name].locations[key]; ok {
+		if loc.file != "" {
+			d.File = loc.file
+		}
+		if loc.line > 0 {
+			d.Line = loc.line
+		}
+	}
+
+	return d
+}
+
+// checkSelector checks that a decoded selector has everything it needs to be used.
+func checkSelector(s *ConfigSelector) error {
+	if s.ReadExternalConfig {
+		return nil
+	}
+
+	switch {
+	case s.TargetVar == "" && len(s.PossibleValues) == 0:
+		return errors.New("`target_var` and `possible_values` are not set")
+	case s.TargetVar == "":
+		return errors.New("`target_var` is not set")
+	case len(s.PossibleValues) == 0:
+		return errors.New("`possible_values` is not set or empty")
+	}
+
+	return nil
+}
+
+// checkSection checks the raw values of a section as it was read from a single config file.
+//
+// The lines map the dotted keys of the file to the lines they are defined on.
+func checkSection(name string, s *section, lines map[string]int) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	report := func(key string, message string, args ...any) {
+		path := name + "." + key
+		line, ok := lines[path]
+		if !ok {
+			line = lines[name]
+		}
+		diagnostics = append(diagnostics, Diagnostic{Line: line, Key: path, Message: fmt.Sprintf(message, args...)})
+	}
+
+	known := selectorKeys()
+	for key := range s.raw {
+		if !slices.Contains(known, key) {
+			report(key, "unknown key (known keys: %s)", strings.Join(known, ", "))
+		}
+	}
+
+	if _, err := decodeSelector(name, s.raw); err != nil {
+		diagnostics = append(diagnostics, Diagnostic{Line: lines[name], Key: name, Message: err.Error()})
+	}
+
+	// whether the target variable is a valid name is checked once it is expanded, see Config.Validate
+	if value, ok := s.raw["target_var"]; ok && strings.TrimSpace(fmt.Sprint(value)) == "" {
+		report("target_var", "value is empty")
+	}
+
+	seen := make(map[string]bool)
+	for _, value := range toSlice(s.raw["possible_values"]) {
+		v := fmt.Sprint(value)
+		switch {
+		case strings.TrimSpace(v) == "":
+			report("possible_values", "contains an empty value")
+		case seen[v]:
+			report("possible_values", "contains %q more than once", v)
+		}
+		seen[v] = true
+	}
+
+	return diagnostics
+}
+
+// selectorKeys returns the keys a selector section may contain: the keys of ConfigSelector
+// and the keys controlling how the section is layered.
+func selectorKeys() []string {
+	keys := []string{"merge", "locked"}
+
+	t := reflect.TypeOf(ConfigSelector{})
+	for i := 0; i < t.NumField(); i++ {
+		if tag := t.Field(i).Tag.Get("mapstructure"); tag != "" && tag != "-" {
+			keys = append(keys, tag)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// tomlKeyLines maps the dotted, lowercased keys of a TOML document to the lines they are defined on.
+//
+// Tables are mapped by their name, keys within tables by the table name and the key.
+// Parse errors are ignored, as the document is parsed by viper beforehand.
+func tomlKeyLines(data []byte) map[string]int {
+	lines := make(map[string]int)
+
+	p := unstable.Parser{}
+	p.Reset(data)
+
+	var table []string
+	for p.NextExpression() {
+		e := p.Expression()
+
+		switch e.Kind {
+		case unstable.Table, unstable.ArrayTable:
+			table = nil
+			line := 0
+			for it := e.Key(); it.Next(); {
+				key := it.Node()
+				if line == 0 {
+					line = p.Shape(key.Raw).Start.Line
+				}
+				table = append(table, strings.ToLower(string(key.Data)))
+			}
+			lines[strings.Join(table, ".")] = line
+		case unstable.KeyValue:
+			path := slices.Clone(table)
+			line := 0
+			for it := e.Key(); it.Next(); {
+				key := it.Node()
+				if line == 0 {
+					line = p.Shape(key.Raw).Start.Line
+				}
+				path = append(path, strings.ToLower(string(key.Data)))
+			}
+			lines[strings.Join(path, ".")] = line
+		}
+	}
+
+	return lines
+}
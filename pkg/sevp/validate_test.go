@@ -0,0 +1,134 @@
+package sevp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Validating a config should report every problem with its file, line and key
+func TestValidate(t *testing.T) {
+	root := newTestRoot()
+	writeTestFiles(t, root, map[string]string{
+		"/home/test/sevp.toml": `default = "nope"
+
+[aws]
+external_config = true
+
+[foo]
+target_var = "1BAD"
+possible_values = ["a", "", "a"]
+colour = "x"
+
+[bar]
+possible_values = ["x"]
+
+[mystery]
+external_config = true
+`,
+	})
+
+	cfg, err := LoadConfig(root, "/home/test/sevp.toml")
+	require.NoError(t, err)
+
+	var got []string
+	for _, diagnostic := range cfg.Validate() {
+		got = append(got, diagnostic.String())
+	}
+
+	assert.Equal(t, []string{
+		`/home/test/sevp.toml:1: default: default selector "nope" is not defined`,
+		`/home/test/sevp.toml:7: foo.target_var: "1BAD" is not a valid environment variable name`,
+		`/home/test/sevp.toml:8: foo.possible_values: contains an empty value`,
+		`/home/test/sevp.toml:8: foo.possible_values: contains "a" more than once`,
+		`/home/test/sevp.toml:9: foo.colour: unknown key (known keys: dangerous, dangerous_color, external_config, locked, merge, meta, possible_values, target_var, value_template)`,
+		"/home/test/sevp.toml:11: bar: `target_var` is not set",
+		`/home/test/sevp.toml:14: mystery: external_config is set, but there is no provider named "mystery" (available: aws, docker-context, goenv, tfenv)`,
+	}, got)
+}
+
+// Validating a layered config should check the merged selectors and report problems in the file they occur in
+func TestValidateLayeredConfig(t *testing.T) {
+	root := newTestRoot()
+	root.Workdir = "/home/test/repo"
+	t.Setenv("SEVP_SYSTEM_CONFIG", "")
+	t.Setenv("SEVP_TEAM_CONFIG", "")
+
+	writeTestFiles(t, root, map[string]string{
+		"/home/test/.config/sevp.toml": `
+[aws]
+target_var = "AWS_PROFILE"
+`,
+		"/home/test/repo/.git/HEAD": "",
+		"/home/test/repo/.sevp.toml": `
+[aws]
+possible_values = ["dev"]
+
+[kube]
+target_var = "KUBECONFIG"
+possible_values = [1, [2]]
+`,
+	})
+
+	cfg, err := InitConfig(root, "/home/test/.config/sevp.toml")
+	require.NoError(t, err)
+
+	diagnostics := cfg.Validate()
+	require.Len(t, diagnostics, 1, "aws is only valid once merged, kube has an invalid value")
+	assert.Equal(t, "/home/test/repo/.sevp.toml", diagnostics[0].File)
+	assert.Equal(t, 5, diagnostics[0].Line)
+	assert.Equal(t, "kube", diagnostics[0].Key)
+}
+
+// A valid config should not have any problems
+func TestValidateValidConfig(t *testing.T) {
+	root := newTestRoot()
+	writeTestFiles(t, root, map[string]string{
+		"/home/test/sevp.toml": `
+default = "aws"
+
+[aws]
+external_config = true
+
+[kube]
+target_var = "KUBECONFIG"
+possible_values = ["dev", "prod"]
+`,
+	})
+
+	cfg, err := LoadConfig(root, "/home/test/sevp.toml")
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Validate())
+}
+
+// Diagnostics should omit the parts that are unknown
+func TestDiagnosticString(t *testing.T) {
+	assert.Equal(t, "a.toml:3: aws.target_var: bad", Diagnostic{File: "a.toml", Line: 3, Key: "aws.target_var", Message: "bad"}.String())
+	assert.Equal(t, "a.toml: aws: bad", Diagnostic{File: "a.toml", Key: "aws", Message: "bad"}.String())
+	assert.Equal(t, "bad", Diagnostic{Message: "bad"}.String())
+}
+
+// Key lines should be found for tables, keys in tables and dotted keys
+func TestTOMLKeyLines(t *testing.T) {
+	lines := tomlKeyLines([]byte(`default = "aws"
+
+[AWS]
+target_var = "X"
+possible_values = [
+  "a",
+]
+
+[a.b]
+c.d = 1
+`))
+
+	assert.Equal(t, map[string]int{
+		"default":             1,
+		"aws":                 3,
+		"aws.target_var":      4,
+		"aws.possible_values": 5,
+		"a.b":                 9,
+		"a.b.c.d":             10,
+	}, lines)
+}